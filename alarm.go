@@ -1,10 +1,7 @@
 package kis
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
+	"context"
 	"time"
 )
 
@@ -19,83 +16,51 @@ type Alarm struct {
 
 // GetHistoricalAlarmByMobilePhone retrieves historical alarm information by mobile phone number.
 func (k *Kubota) GetHistoricalAlarmByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
-	return k.getAlarm("mobilePhone", mobilePhone, subscription, startDate, endDate)
+	return payload(k.getAlarm(context.Background(), "mobilePhone", mobilePhone, subscription, startDate, endDate))
+}
+
+// GetHistoricalAlarmByMobilePhoneContext is like GetHistoricalAlarmByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalAlarmByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
+	return payload(k.getAlarm(ctx, "mobilePhone", mobilePhone, subscription, startDate, endDate))
+}
+
+// GetHistoricalAlarmByMobilePhoneWithMeta is like GetHistoricalAlarmByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalAlarmByMobilePhoneWithMeta(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Alarm, ResponseMeta, error) {
+	return k.getAlarm(ctx, "mobilePhone", mobilePhone, subscription, startDate, endDate)
 }
 
 // GetHistoricalAlarmByUserName retrieves historical alarm information by username.
 func (k *Kubota) GetHistoricalAlarmByUserName(userName, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
-	return k.getAlarm("userName", userName, subscription, startDate, endDate)
+	return payload(k.getAlarm(context.Background(), "userName", userName, subscription, startDate, endDate))
 }
 
-// GetHistoricalAlarmByMachineUUID retrieves historical alarm information by machine UUID.
-func (k *Kubota) GetHistoricalAlarmByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
-	return k.getAlarm("machineUUID", machineUUID, subscription, startDate, endDate)
+// GetHistoricalAlarmByUserNameContext is like GetHistoricalAlarmByUserName but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalAlarmByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
+	return payload(k.getAlarm(ctx, "userName", userName, subscription, startDate, endDate))
 }
 
-// getAlarm is a helper function to retrieve alarm information based on a given field.
-func (k *Kubota) getAlarm(field, value, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/alarm?%s=%s", k.authentication.Endpoint, field, value)
-	if subscription != "" {
-		apiURL += "&subscription=" + subscription
-	}
-	if !startDate.IsZero() {
-		s := CustomTime{startDate}
-		ss, err := s.MarshalJSON()
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling start date: %w", err)
-		}
-		apiURL += "&startDate=" + string(ss)
-	}
-	if !endDate.IsZero() {
-		e := CustomTime{endDate}
-		ee, err := e.MarshalJSON()
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling end date: %w", err)
-		}
-		apiURL += "&endDate=" + string(ee)
-	}
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating alarm request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
+// GetHistoricalAlarmByUserNameWithMeta is like GetHistoricalAlarmByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalAlarmByUserNameWithMeta(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Alarm, ResponseMeta, error) {
+	return k.getAlarm(ctx, "userName", userName, subscription, startDate, endDate)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making alarm request: %w", err)
-	}
-	defer resp.Body.Close()
+// GetHistoricalAlarmByMachineUUID retrieves historical alarm information by machine UUID.
+func (k *Kubota) GetHistoricalAlarmByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
+	return payload(k.getAlarm(context.Background(), "machineUUID", machineUUID, subscription, startDate, endDate))
+}
 
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return nil, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return nil, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
+// GetHistoricalAlarmByMachineUUIDContext is like GetHistoricalAlarmByMachineUUID but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalAlarmByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Alarm, error) {
+	return payload(k.getAlarm(ctx, "machineUUID", machineUUID, subscription, startDate, endDate))
+}
 
-	// Unmarshal the response
-	var alarmResponse struct {
-		Status   int     `json:"Status"`
-		Resource string  `json:"Resource"`
-		Payload  []Alarm `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&alarmResponse); err != nil {
-		return nil, fmt.Errorf("error decoding alarm response: %w", err)
-	}
+// GetHistoricalAlarmByMachineUUIDWithMeta is like GetHistoricalAlarmByMachineUUIDContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalAlarmByMachineUUIDWithMeta(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Alarm, ResponseMeta, error) {
+	return k.getAlarm(ctx, "machineUUID", machineUUID, subscription, startDate, endDate)
+}
 
-	// Return the alarm information
-	return alarmResponse.Payload, nil
+// getAlarm is a helper function to retrieve alarm information based on a given field.
+func (k *Kubota) getAlarm(ctx context.Context, field, value, subscription string, startDate, endDate time.Time) ([]Alarm, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetHistoricalAlarm", Endpoint: "alarm", Field: field, Subscription: subscription, StartDate: startDate, EndDate: endDate}
+	return do[[]Alarm](ctx, k, call, value)
 }
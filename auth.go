@@ -1,9 +1,10 @@
 package kis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 
 	"errors"
@@ -13,16 +14,135 @@ import (
 	"time"
 )
 
+// tokenExpiryMargin is the time before the expiry at which a token is refreshed.
+const tokenExpiryMargin = time.Minute
+
+// tokenRequestTimeout bounds a token refresh. The refresh is detached from the context of the
+// caller, which may have no deadline at all.
+var tokenRequestTimeout = time.Minute
+
+// authentication caches the token of a TokenSource for the Kubota client.
 type authentication struct {
-	PublicKey   string
-	SecretKey   string
-	Endpoint    string
+	source      TokenSource
+	logger      *slog.Logger
+	metrics     MetricsCollector
 	Token       string
 	TokenType   string
-	ExpiresIn   int
 	TokenExpiry time.Time
 	// Mutex to protect the token during refresh
 	TokenMutex sync.Mutex
+	// refresh is the token request in flight, nil if there is none
+	refresh *tokenRefresh
+	// closeCtx is cancelled by close to abort running token requests
+	closeCtx context.Context
+	close    context.CancelFunc
+}
+
+// tokenRefresh is a single token request shared by all concurrent callers.
+type tokenRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func newAuthentication(source TokenSource, logger *slog.Logger, metrics MetricsCollector) *authentication {
+	a := &authentication{
+		source:  source,
+		logger:  logger,
+		metrics: metrics,
+	}
+	a.closeCtx, a.close = context.WithCancel(context.Background())
+	return a
+}
+
+// token returns a valid access token. The token is requested on demand if there is none yet
+// or if it expires soon; concurrent callers share a single token request.
+func (a *authentication) token(ctx context.Context) (string, error) {
+	a.TokenMutex.Lock()
+	if a.closeCtx.Err() != nil {
+		a.TokenMutex.Unlock()
+		return "", ErrClientClosed
+	}
+	if (&Token{AccessToken: a.Token, Expiry: a.TokenExpiry}).Valid() {
+		t := a.Token
+		a.TokenMutex.Unlock()
+		return t, nil
+	}
+	r := a.refresh
+	if r == nil {
+		r = &tokenRefresh{done: make(chan struct{})}
+		a.refresh = r
+		// the request is detached from the cancellation of ctx, as other callers may wait for it,
+		// and bounded by tokenRequestTimeout instead
+		go a.accessToken(context.WithoutCancel(ctx), r)
+	}
+	a.TokenMutex.Unlock()
+
+	select {
+	case <-r.done:
+		return r.token, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-a.closeCtx.Done():
+		return "", ErrClientClosed
+	}
+}
+
+// invalidate drops the token if it is still the given one, e.g. after the API rejected it.
+func (a *authentication) invalidate(token string) {
+	a.TokenMutex.Lock()
+	if a.Token == token {
+		a.Token = ""
+	}
+	a.TokenMutex.Unlock()
+	if inv, ok := a.source.(tokenInvalidator); ok {
+		inv.invalidate(token)
+	}
+}
+
+// accessToken retrieves a new access token from the token source and stores the result in r.
+func (a *authentication) accessToken(ctx context.Context, r *tokenRefresh) {
+	ctx, cancel := context.WithTimeout(ctx, tokenRequestTimeout)
+	defer cancel()
+	stop := context.AfterFunc(a.closeCtx, cancel)
+	defer stop()
+
+	start := time.Now()
+	t, err := a.source.Token(ctx)
+	a.metrics.ObserveTokenRefresh(err)
+	if err == nil {
+		a.logger.LogAttrs(ctx, slog.LevelInfo, "access token refreshed",
+			slog.Time("expiry", t.Expiry), slog.Duration("latency", time.Since(start)))
+	} else {
+		a.logger.LogAttrs(ctx, slog.LevelError, "error refreshing access token",
+			slog.Any("error", err), slog.Duration("latency", time.Since(start)))
+	}
+
+	a.TokenMutex.Lock()
+	switch {
+	case err == nil:
+		a.Token = t.AccessToken
+		a.TokenType = t.TokenType
+		a.TokenExpiry = t.Expiry
+		r.token = t.AccessToken
+	case a.closeCtx.Err() != nil:
+		// the request was aborted by close
+		r.err = ErrClientClosed
+	default:
+		r.err = errors.Join(err, errors.New("error with authentication"))
+	}
+	a.refresh = nil
+	a.TokenMutex.Unlock()
+	close(r.done)
+}
+
+// keyPairTokenSource requests tokens with the public and secret key of a developer app.
+type keyPairTokenSource struct {
+	PublicKey string
+	SecretKey string
+	Endpoint  string
+	exec      *executor
+	apiPath   string
 }
 
 // tokenResponse represents the JSON response from the token endpoint.
@@ -32,102 +152,62 @@ type tokenResponse struct {
 	ExpiresIn   int    `json:"ExpiresIn"`
 }
 
-func newAuthentication(publicKey, SecretKey, Endpoint string) (*authentication, error) {
-	a := &authentication{
+// NewKeyPairTokenSource returns a TokenSource which requests a new token from the
+// token endpoint of the API on every call, which is what NewKIS uses by default.
+// The options configure the HTTP requests like for NewKIS.
+func NewKeyPairTokenSource(publicKey, SecretKey, Endpoint string, opts ...Option) TokenSource {
+	cfg := newConfig(opts)
+	return &keyPairTokenSource{
 		PublicKey: publicKey,
 		SecretKey: SecretKey,
 		Endpoint:  Endpoint,
+		exec:      cfg.executor(),
+		apiPath:   cfg.apiPath,
 	}
-	err := a.accessToken()
-	if err != nil {
-		return nil, errors.Join(err, errors.New("error with authentication"))
-	}
-	go a.refreshAccessToken()
-
-	return a, nil
-}
-func (a *authentication) getToken() string {
-	a.TokenMutex.Lock()
-	t := a.Token
-	defer a.TokenMutex.Unlock()
-	return t
 }
 
-// refreshToken retrieves a new access token from the Kubota API.
-func (a *authentication) accessToken() error {
-	a.TokenMutex.Lock()
-	tokenURL := a.Endpoint
+// Token implements the TokenSource interface by calling the token endpoint of the Kubota API.
+func (s *keyPairTokenSource) Token(ctx context.Context) (*Token, error) {
+	tokenURL := s.Endpoint
 	// Construct the request URL and payload
 	data := url.Values{}
 	data.Set("grantType", "authorization_code")
-	data.Set("publicKey", a.PublicKey)
-	data.Set("secretKey", a.SecretKey)
+	data.Set("publicKey", s.PublicKey)
+	data.Set("secretKey", s.SecretKey)
 
 	u, err := url.Parse(tokenURL)
 	if err != nil {
-		return fmt.Errorf("error parsing token URL: %w", err)
+		return nil, fmt.Errorf("error parsing token URL: %w", err)
 	}
-	u.Path = "/api/v1/authorization/token"
+	u.Path = s.apiPath + "/authorization/token"
 	// Make the request
-	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(data.Encode()))
-	if err != nil {
-		return fmt.Errorf("error creating token request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.exec.do(ctx, RequestInfo{Endpoint: "token"}, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(data.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
 	if err != nil {
-		return fmt.Errorf("error making token request: %w", err)
+		return nil, fmt.Errorf("error making token request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Handle the response
 	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
+		return nil, newAPIError(resp)
 	}
 
 	// Unmarshal the response
 	var tokenResponse tokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
-		return fmt.Errorf("error decoding token response: %w", err)
-	}
-
-	// Update the configuration with the new token information
-	a.Token = tokenResponse.AccessToken
-	a.TokenType = tokenResponse.TokenType
-	a.ExpiresIn = tokenResponse.ExpiresIn
-	a.TokenExpiry = time.Now().Add(time.Duration(a.ExpiresIn) * time.Minute)
-	a.TokenMutex.Unlock()
-	return nil
-}
-
-// refreshAccessToken periodically refreshes the access token.
-func (a *authentication) refreshAccessToken() {
-	for {
-		// check if token is 1 minute before expiry
-		if time.Now().After(a.TokenExpiry.Add(-1 * time.Minute)) {
-
-			// Refresh the token
-			if err := a.accessToken(); err != nil {
-				// Handle refresh errors
-				log.Fatal(errors.Join(err, errors.New("error refreshing access token")))
-			} else {
-				// Log the successful refresh
-				log.Println("Access token refreshed")
-			}
-		}
-		// Sleep until the next refresh attempt, using the ExpiresIn value
-		time.Sleep(time.Duration(a.ExpiresIn-1) * time.Minute) // Subtract 1 minute for a safety margin
+		return nil, fmt.Errorf("error decoding token response: %w", err)
 	}
+	return &Token{
+		AccessToken: tokenResponse.AccessToken,
+		TokenType:   tokenResponse.TokenType,
+		// ExpiresIn is given in minutes
+		Expiry: time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Minute),
+	}, nil
 }
@@ -0,0 +1,217 @@
+package kis_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"github.com/maltegrosse/go-kubota-kis-api/kisfake"
+	"github.com/maltegrosse/go-kubota-kis-api/kistest"
+)
+
+// countingSource requests tokens from a kistest server and counts the calls. The first token it
+// returns is already expired. Further calls wait for release if it is set.
+type countingSource struct {
+	next    kis.TokenSource
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *countingSource) Token(ctx context.Context) (*kis.Token, error) {
+	n := s.calls.Add(1)
+	if n > 1 && s.release != nil {
+		s.started <- struct{}{}
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	t, err := s.next.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n == 1 {
+		t.Expiry = time.Now().Add(-time.Second)
+	}
+	return t, nil
+}
+
+func newAuthServer(t *testing.T) *kistest.Server {
+	t.Helper()
+	return kistest.Start(t, &kisfake.Client{Positions: []kis.Position{
+		{MachineUUID: "m1", Latitude: 52.1, Longitude: 8.1, Timestamp: kis.CustomTime{Time: time.Now()}},
+	}})
+}
+
+func newAuthClient(t *testing.T, srv *kistest.Server, source *countingSource) *kis.Kubota {
+	t.Helper()
+	source.next = kis.NewKeyPairTokenSource("public", "secret", srv.URL)
+	return srv.NewClient(t, kis.WithTokenSource(source), kis.WithRetryPolicy(kis.NoRetry))
+}
+
+func TestConcurrentRefreshOfExpiredToken(t *testing.T) {
+	srv := newAuthServer(t)
+	source := &countingSource{started: make(chan struct{}, 1), release: make(chan struct{})}
+	k := newAuthClient(t, srv, source)
+
+	const callers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m1", "")
+			errs <- err
+		}()
+	}
+	<-source.started
+	// give the other callers time to queue up behind the refresh in flight
+	time.Sleep(50 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := source.calls.Load(); n != 2 {
+		t.Errorf("token source calls = %d, want 1 for NewKIS and 1 for the refresh", n)
+	}
+	if n := srv.Requests("token"); n != 2 {
+		t.Errorf("token requests = %d, want 2", n)
+	}
+	if n := srv.Requests("position"); n != callers {
+		t.Errorf("position requests = %d, want %d", n, callers)
+	}
+}
+
+func TestCloseDuringRefresh(t *testing.T) {
+	srv := newAuthServer(t)
+	source := &countingSource{started: make(chan struct{}, 1), release: make(chan struct{})}
+	k := newAuthClient(t, srv, source)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m1", "")
+			errs <- err
+		}()
+	}
+	<-source.started
+	k.Close()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, kis.ErrClientClosed) {
+				t.Errorf("error = %v, want ErrClientClosed", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("caller still blocked after Close")
+		}
+	}
+	if _, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m1", ""); !errors.Is(err, kis.ErrClientClosed) {
+		t.Errorf("error after Close = %v, want ErrClientClosed", err)
+	}
+	if n := srv.Requests("position"); n != 0 {
+		t.Errorf("position requests = %d, want 0", n)
+	}
+}
+
+func TestReauthenticateOnceOnUnauthorized(t *testing.T) {
+	srv := newAuthServer(t)
+	k := srv.NewClient(t, kis.WithRetryPolicy(kis.NoRetry))
+	ctx := context.Background()
+
+	// an expired token is replaced and the request succeeds
+	srv.ExpireTokens()
+	if _, err := k.GetLastPositionByMachineUUIDContext(ctx, "m1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests("token"); n != 2 {
+		t.Errorf("token requests = %d, want 2", n)
+	}
+
+	// a 401 persisting after the re-authentication is returned without another one
+	srv.Fail("position", kistest.Failure{Status: 401, Times: 5})
+	_, err := k.GetLastPositionByMachineUUIDContext(ctx, "m1", "")
+	if !errors.Is(err, kis.ErrUnauthorized) {
+		t.Fatalf("error = %v, want ErrUnauthorized", err)
+	}
+	if n := srv.Requests("token"); n != 3 {
+		t.Errorf("token requests = %d, want 3", n)
+	}
+	if n := srv.Requests("position"); n != 4 {
+		t.Errorf("position requests = %d, want 4", n)
+	}
+}
+
+func TestTokenCacheFile(t *testing.T) {
+	srv := newAuthServer(t)
+	path := filepath.Join(t.TempDir(), "token.json")
+	ctx := context.Background()
+	k := srv.NewClient(t, kis.WithTokenCacheFile(path), kis.WithRetryPolicy(kis.NoRetry))
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("token not cached: %v", err)
+	}
+
+	// another client reuses the cached token
+	srv.NewClient(t, kis.WithTokenCacheFile(path))
+	if n := srv.Requests("token"); n != 1 {
+		t.Errorf("token requests = %d, want 1", n)
+	}
+
+	// a token rejected with 401 is removed from the cache and replaced
+	srv.ExpireTokens()
+	if _, err := k.GetLastPositionByMachineUUIDContext(ctx, "m1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests("token"); n != 2 {
+		t.Errorf("token requests = %d, want 2", n)
+	}
+	second, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("new token not cached: %v", err)
+	}
+	if string(first) == string(second) {
+		t.Error("cache file still holds the rejected token")
+	}
+}
+
+// blockingSource never answers until its context is done.
+type blockingSource struct{}
+
+func (blockingSource) Token(ctx context.Context) (*kis.Token, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRefreshTimeout(t *testing.T) {
+	defer kis.SetTokenRequestTimeout(50 * time.Millisecond)()
+	srv := newAuthServer(t)
+	k := srv.NewClient(t, kis.WithTokenSource(blockingSource{}), kis.WithLazyAuthentication())
+	done := make(chan error, 1)
+	go func() {
+		// the getter without context has no deadline of its own
+		_, err := k.GetLastPositionByMachineUUID("m1", "")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("getter still blocked by a hung token request")
+	}
+}
@@ -0,0 +1,162 @@
+package kis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChunkWindow is the window size used if ChunkOptions.Window is not set.
+const DefaultChunkWindow = 24 * time.Hour
+
+// ChunkOptions configures how the chunked getters split a date range.
+type ChunkOptions struct {
+	// Window is the length of a single request window, DefaultChunkWindow if zero.
+	Window time.Duration
+	// Concurrency is the number of windows fetched in parallel, 1 if zero.
+	Concurrency int
+}
+
+// WindowError is the error of a single window of a chunked request.
+type WindowError struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Err       error
+}
+
+// Error implements the error interface.
+func (e *WindowError) Error() string {
+	return fmt.Sprintf("window %s - %s: %s", formatTime(e.StartDate), formatTime(e.EndDate), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *WindowError) Unwrap() error {
+	return e.Err
+}
+
+// ChunkError is returned by the chunked getters if some windows failed. The results of the
+// other windows are returned along with it.
+type ChunkError struct {
+	Windows []*WindowError
+}
+
+// Error implements the error interface.
+func (e *ChunkError) Error() string {
+	msgs := make([]string, len(e.Windows))
+	for i, w := range e.Windows {
+		msgs[i] = w.Error()
+	}
+	return fmt.Sprintf("error in %d windows: %s", len(e.Windows), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed windows, so errors.Is and errors.As match any of them.
+func (e *ChunkError) Unwrap() []error {
+	errs := make([]error, len(e.Windows))
+	for i, w := range e.Windows {
+		errs[i] = w
+	}
+	return errs
+}
+
+// GetHistoricalPositionByMachineUUIDChunked is like GetHistoricalPositionByMachineUUIDContext but splits the date
+// range into windows. The merged positions are deduplicated by MachineUUID and Timestamp. If some windows fail,
+// the positions of the others are returned with a *ChunkError.
+func (k *Kubota) GetHistoricalPositionByMachineUUIDChunked(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time, opts ChunkOptions) ([]Position, error) {
+	return chunked(ctx, startDate, endDate, opts, func(ctx context.Context, s, e time.Time) ([]Position, error) {
+		return payload(k.getPositions(ctx, "machineUUID", machineUUID, subscription, s, e))
+	}, positionKey)
+}
+
+// GetHistoricalMeasureByMachineUUIDChunked is like GetHistoricalMeasureByMachineUUIDContext but splits the date
+// range into windows. The merged measures are deduplicated by MachineUUID, MeasureName and Timestamp, as a machine
+// reports several measures at once. If some windows fail, the measures of the others are returned with a *ChunkError.
+func (k *Kubota) GetHistoricalMeasureByMachineUUIDChunked(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time, opts ChunkOptions) ([]Measure, error) {
+	return chunked(ctx, startDate, endDate, opts, func(ctx context.Context, s, e time.Time) ([]Measure, error) {
+		return payload(k.getMeasure(ctx, "machineUUID", machineUUID, subscription, s, e))
+	}, measureKey)
+}
+
+// GetHistoricalAlarmByMachineUUIDChunked is like GetHistoricalAlarmByMachineUUIDContext but splits the date
+// range into windows. The merged alarms are deduplicated by MachineUUID, Type and Timestamp. If some windows fail,
+// the alarms of the others are returned with a *ChunkError.
+func (k *Kubota) GetHistoricalAlarmByMachineUUIDChunked(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time, opts ChunkOptions) ([]Alarm, error) {
+	return chunked(ctx, startDate, endDate, opts, func(ctx context.Context, s, e time.Time) ([]Alarm, error) {
+		return payload(k.getAlarm(ctx, "machineUUID", machineUUID, subscription, s, e))
+	}, alarmKey)
+}
+
+func positionKey(p Position) string {
+	return p.MachineUUID + "|" + strconv.FormatInt(p.Timestamp.UnixNano(), 10)
+}
+
+func measureKey(m Measure) string {
+	return m.MachineUUID + "|" + m.MeasureName + "|" + strconv.FormatInt(m.Timestamp.UnixNano(), 10)
+}
+
+func alarmKey(a Alarm) string {
+	return a.MachineUUID + "|" + a.Type + "|" + strconv.FormatInt(a.Timestamp.UnixNano(), 10)
+}
+
+// window is a part of a date range.
+type window struct {
+	start, end time.Time
+}
+
+// splitRange splits the date range into consecutive windows of length size.
+// Adjacent windows share their boundary, duplicates are removed when merging.
+func splitRange(startDate, endDate time.Time, size time.Duration) []window {
+	var windows []window
+	for s := startDate; s.Before(endDate); s = s.Add(size) {
+		e := s.Add(size)
+		if e.After(endDate) {
+			e = endDate
+		}
+		windows = append(windows, window{start: s, end: e})
+	}
+	return windows
+}
+
+// chunked fetches the date range window by window with bounded concurrency and merges the
+// results in window order, dropping items with a key seen before.
+func chunked[T any](ctx context.Context, startDate, endDate time.Time, opts ChunkOptions, fetch func(ctx context.Context, startDate, endDate time.Time) ([]T, error), key func(T) string) ([]T, error) {
+	if startDate.IsZero() || endDate.IsZero() || !startDate.Before(endDate) {
+		return nil, errors.New("error splitting date range: start date must be before end date")
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultChunkWindow
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	windows := splitRange(startDate, endDate, opts.Window)
+	results := make([][]T, len(windows))
+	errs := parallel(ctx, len(windows), opts.Concurrency, func(ctx context.Context, i int) (err error) {
+		results[i], err = fetch(ctx, windows[i].start, windows[i].end)
+		return err
+	})
+
+	var merged []T
+	seen := make(map[string]bool)
+	chunkErr := &ChunkError{}
+	for i, items := range results {
+		if errs[i] != nil {
+			chunkErr.Windows = append(chunkErr.Windows, &WindowError{StartDate: windows[i].start, EndDate: windows[i].end, Err: errs[i]})
+			continue
+		}
+		for _, it := range items {
+			k := key(it)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			merged = append(merged, it)
+		}
+	}
+	if len(chunkErr.Windows) > 0 {
+		return merged, chunkErr
+	}
+	return merged, nil
+}
@@ -0,0 +1,183 @@
+package kis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var chunkStart = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// hourly returns a time every hour from s to e, both included, like the API does for a date range.
+func hourly(_ context.Context, s, e time.Time) ([]time.Time, error) {
+	var ts []time.Time
+	for t := s; !t.After(e); t = t.Add(time.Hour) {
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+func timeKey(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// hours returns the hours after chunkStart of the times.
+func hours(ts []time.Time) []int {
+	var h []int
+	for _, t := range ts {
+		h = append(h, int(t.Sub(chunkStart)/time.Hour))
+	}
+	return h
+}
+
+func TestSplitRange(t *testing.T) {
+	tests := []struct {
+		name string
+		end  time.Duration
+		size time.Duration
+		want [][2]int
+	}{
+		{"multiple of the window", 48 * time.Hour, 24 * time.Hour, [][2]int{{0, 24}, {24, 48}}},
+		{"not a multiple of the window", 50 * time.Hour, 24 * time.Hour, [][2]int{{0, 24}, {24, 48}, {48, 50}}},
+		{"shorter than the window", 5 * time.Hour, 24 * time.Hour, [][2]int{{0, 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][2]int
+			for _, w := range splitRange(chunkStart, chunkStart.Add(tt.end), tt.size) {
+				got = append(got, [2]int{int(w.start.Sub(chunkStart).Hours()), int(w.end.Sub(chunkStart).Hours())})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkedDeduplicatesBoundaries(t *testing.T) {
+	// 50 hours in windows of 24 hours; the last window is shorter
+	got, err := chunked(context.Background(), chunkStart, chunkStart.Add(50*time.Hour), ChunkOptions{Window: 24 * time.Hour}, hourly, timeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]int, 51)
+	for i := range want {
+		want[i] = i
+	}
+	if !reflect.DeepEqual(hours(got), want) {
+		t.Errorf("chunked() = hours %v, want 0 to 50 once each", hours(got))
+	}
+}
+
+func TestChunkedMergeOrder(t *testing.T) {
+	// later windows finish first
+	fetch := func(ctx context.Context, s, e time.Time) ([]time.Time, error) {
+		time.Sleep(time.Duration(72-s.Sub(chunkStart).Hours()) * time.Millisecond)
+		return hourly(ctx, s, e)
+	}
+	var running, peak atomic.Int32
+	tracked := func(ctx context.Context, s, e time.Time) ([]time.Time, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		return fetch(ctx, s, e)
+	}
+	got, err := chunked(context.Background(), chunkStart, chunkStart.Add(72*time.Hour), ChunkOptions{Window: 6 * time.Hour, Concurrency: 4}, tracked, timeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, h := range hours(got) {
+		if h != i {
+			t.Fatalf("item %d is hour %d, want the items in window order", i, h)
+		}
+	}
+	if len(got) != 73 {
+		t.Errorf("got %d items, want 73", len(got))
+	}
+	if p := peak.Load(); p > 4 || p < 2 {
+		t.Errorf("%d windows fetched in parallel, want 2 to 4", p)
+	}
+}
+
+func TestChunkedWindowError(t *testing.T) {
+	errFailed := errors.New("failed")
+	fetch := func(ctx context.Context, s, e time.Time) ([]time.Time, error) {
+		if s.Equal(chunkStart.Add(24 * time.Hour)) {
+			return nil, errFailed
+		}
+		return hourly(ctx, s, e)
+	}
+	for _, concurrency := range []int{1, 3} {
+		t.Run("concurrency "+strconv.Itoa(concurrency), func(t *testing.T) {
+			got, err := chunked(context.Background(), chunkStart, chunkStart.Add(72*time.Hour), ChunkOptions{Window: 24 * time.Hour, Concurrency: concurrency}, fetch, timeKey)
+			var chunkErr *ChunkError
+			if !errors.As(err, &chunkErr) || !errors.Is(err, errFailed) {
+				t.Fatalf("error = %v, want *ChunkError wrapping the window error", err)
+			}
+			if len(chunkErr.Windows) != 1 {
+				t.Fatalf("%d failed windows, want 1", len(chunkErr.Windows))
+			}
+			w := chunkErr.Windows[0]
+			if !w.StartDate.Equal(chunkStart.Add(24*time.Hour)) || !w.EndDate.Equal(chunkStart.Add(48*time.Hour)) {
+				t.Errorf("failed window %v - %v, want the second day", w.StartDate, w.EndDate)
+			}
+			// the items of the other windows are returned, including the boundaries of the failed one
+			var want []int
+			for h := 0; h <= 24; h++ {
+				want = append(want, h)
+			}
+			for h := 48; h <= 72; h++ {
+				want = append(want, h)
+			}
+			if !reflect.DeepEqual(hours(got), want) {
+				t.Errorf("chunked() = hours %v, want %v", hours(got), want)
+			}
+		})
+	}
+}
+
+func TestChunkedCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, s, e time.Time) ([]time.Time, error) {
+		if calls.Add(1) == 2 {
+			cancel()
+		}
+		return hourly(ctx, s, e)
+	}
+	got, err := chunked(ctx, chunkStart, chunkStart.Add(96*time.Hour), ChunkOptions{Window: 24 * time.Hour}, fetch, timeKey)
+	var chunkErr *ChunkError
+	if !errors.As(err, &chunkErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want *ChunkError wrapping context.Canceled", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fetched %d windows, want 2", n)
+	}
+	if len(chunkErr.Windows) != 2 || len(got) != 49 {
+		t.Errorf("%d failed windows and %d items, want 2 and 49", len(chunkErr.Windows), len(got))
+	}
+}
+
+func TestChunkedInvalidRange(t *testing.T) {
+	for _, r := range [][2]time.Time{
+		{chunkStart, chunkStart},
+		{chunkStart.Add(time.Hour), chunkStart},
+		{time.Time{}, chunkStart},
+		{chunkStart, time.Time{}},
+	} {
+		called := false
+		fetch := func(ctx context.Context, s, e time.Time) ([]time.Time, error) {
+			called = true
+			return nil, nil
+		}
+		if _, err := chunked(context.Background(), r[0], r[1], ChunkOptions{}, fetch, timeKey); err == nil || called {
+			t.Errorf("chunked(%v, %v) error = %v, fetched %v, want an error without fetching", r[0], r[1], err, called)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package kis
+
+import (
+	"context"
+	"time"
+)
+
+// Positions is the part of the Kubota API client to retrieve position information.
+type Positions interface {
+	GetLastPositionByMobilePhone(mobilePhone string, subscription string) (*Position, error)
+	GetLastPositionByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (*Position, error)
+	GetLastPositionByUserName(userName string, subscription string) (*Position, error)
+	GetLastPositionByUserNameContext(ctx context.Context, userName string, subscription string) (*Position, error)
+	GetLastPositionByMachineUUID(machineUUID string, subscription string) (*Position, error)
+	GetLastPositionByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (*Position, error)
+	GetHistoricalPositionByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]Position, error)
+	GetHistoricalPositionByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Position, error)
+	GetHistoricalPositionByUserName(userName, subscription string, startDate, endDate time.Time) ([]Position, error)
+	GetHistoricalPositionByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Position, error)
+	GetHistoricalPositionByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Position, error)
+	GetHistoricalPositionByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Position, error)
+}
+
+// Measures is the part of the Kubota API client to retrieve measure information.
+type Measures interface {
+	GetHistoricalMeasureByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]Measure, error)
+	GetHistoricalMeasureByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Measure, error)
+	GetHistoricalMeasureByUserName(userName, subscription string, startDate, endDate time.Time) ([]Measure, error)
+	GetHistoricalMeasureByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Measure, error)
+	GetHistoricalMeasureByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Measure, error)
+	GetHistoricalMeasureByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Measure, error)
+}
+
+// Alarms is the part of the Kubota API client to retrieve alarm information.
+type Alarms interface {
+	GetHistoricalAlarmByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]Alarm, error)
+	GetHistoricalAlarmByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Alarm, error)
+	GetHistoricalAlarmByUserName(userName, subscription string, startDate, endDate time.Time) ([]Alarm, error)
+	GetHistoricalAlarmByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Alarm, error)
+	GetHistoricalAlarmByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Alarm, error)
+	GetHistoricalAlarmByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Alarm, error)
+}
+
+// Machines is the part of the Kubota API client to retrieve machine information.
+type Machines interface {
+	GetMachineByMobilePhone(mobilePhone string, subscription string) (Machine, error)
+	GetMachineByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (Machine, error)
+	GetMachineByUserName(userName string, subscription string) (Machine, error)
+	GetMachineByUserNameContext(ctx context.Context, userName string, subscription string) (Machine, error)
+	GetMachineByMachineUUID(machineUUID string, subscription string) (Machine, error)
+	GetMachineByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (Machine, error)
+}
+
+// Registries is the part of the Kubota API client to retrieve registry information.
+type Registries interface {
+	GetRegistryByMobilePhone(mobilePhone string, subscription string) (Registry, error)
+	GetRegistryByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (Registry, error)
+	GetRegistryByUserName(userName string, subscription string) (Registry, error)
+	GetRegistryByUserNameContext(ctx context.Context, userName string, subscription string) (Registry, error)
+	GetRegistryByMachineUUID(machineUUID string, subscription string) (Registry, error)
+	GetRegistryByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (Registry, error)
+}
+
+// Users is the part of the Kubota API client to retrieve user information.
+type Users interface {
+	GetUserByMobilePhone(mobilePhone string) (*User, error)
+	GetUserByMobilePhoneContext(ctx context.Context, mobilePhone string) (*User, error)
+	GetUserByUserName(userName string) (*User, error)
+	GetUserByUserNameContext(ctx context.Context, userName string) (*User, error)
+}
+
+// Fields is the part of the Kubota API client to retrieve field information.
+type Fields interface {
+	GetFieldByMobilePhone(mobilePhone string) ([]Field, error)
+	GetFieldByMobilePhoneContext(ctx context.Context, mobilePhone string) ([]Field, error)
+	GetFieldByUserName(userName string) ([]Field, error)
+	GetFieldByUserNameContext(ctx context.Context, userName string) ([]Field, error)
+}
+
+// Client is the interface of the Kubota API client, satisfied by *Kubota.
+// Code depending on it can be tested with the in-memory fake of the kisfake package.
+type Client interface {
+	Positions
+	Measures
+	Alarms
+	Machines
+	Registries
+	Users
+	Fields
+}
+
+var _ Client = (*Kubota)(nil)
@@ -1,5 +1,37 @@
 package kis
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors of this package. Except for ErrClientClosed they are matched against an *APIError with errors.Is.
+var (
+	// ErrUnauthorized is returned for a 401 response, e.g. invalid keys or an expired token.
+	ErrUnauthorized = errors.New("kis: unauthorized")
+	// ErrForbidden is returned for a 403 response.
+	ErrForbidden = errors.New("kis: forbidden")
+	// ErrNotFound is returned for a 404 response.
+	ErrNotFound = errors.New("kis: not found")
+	// ErrRateLimited is returned for a 429 response, see APIError.RetryAfter.
+	ErrRateLimited = errors.New("kis: rate limited")
+	// ErrSubscriptionExpired is returned if the API rejects a request because the subscription of a machine ended.
+	ErrSubscriptionExpired = errors.New("kis: subscription expired")
+	// ErrServer is returned for any 5xx response.
+	ErrServer = errors.New("kis: server error")
+	// ErrClientClosed is returned for calls on a client after Close.
+	ErrClientClosed = errors.New("kis: client closed")
+)
+
+// maxErrorBodySize limits how much of an error response body is read.
+const maxErrorBodySize = 1 << 20
+
 // errorResponse represents the errorResponse information returned by the Kubota API.
 type errorResponse struct {
 	Type    string   `json:"Type"`
@@ -8,3 +40,104 @@ type errorResponse struct {
 	LogID   string   `json:"LogId"`
 	Details []string `json:"Details"`
 }
+
+// APIError is returned for every non-200 response of the Kubota API.
+type APIError struct {
+	// Status is the HTTP status code of the response.
+	Status  int
+	Type    string
+	Title   string
+	LogID   string
+	Details []string
+	// RetryAfter is the parsed Retry-After header, zero if not present.
+	RetryAfter time.Duration
+	// Body is the raw response body.
+	Body []byte
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("error: %s with statuscode: %d, type %s, details: %s", e.Title, e.Status, e.Type, strings.Join(e.Details, ", "))
+	if e.LogID != "" {
+		msg += ", log id: " + e.LogID
+	}
+	if e.RetryAfter > 0 {
+		msg += ", retry after: " + e.RetryAfter.String()
+	}
+	return msg
+}
+
+// Is reports whether the error matches one of the sentinel errors of this package.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.Status == http.StatusForbidden
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	case ErrSubscriptionExpired:
+		return e.subscriptionExpired()
+	case ErrServer:
+		return e.Status >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// subscriptionExpired reports whether the API refused the request because of the subscription.
+// KIS does not use a dedicated status code for this, so the problem details are inspected.
+func (e *APIError) subscriptionExpired() bool {
+	if e.Status != http.StatusForbidden && e.Status != http.StatusPaymentRequired {
+		return false
+	}
+	texts := append([]string{e.Type, e.Title}, e.Details...)
+	for _, t := range texts {
+		if strings.Contains(strings.ToLower(t), "subscription") {
+			return true
+		}
+	}
+	return false
+}
+
+// newAPIError reads the error response body and builds an *APIError from it.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	var errResponse = errorResponse{}
+	// the body is kept as is if it does not contain the expected JSON
+	_ = json.Unmarshal(body, &errResponse)
+	apiErr := &APIError{
+		Status:     resp.StatusCode,
+		Type:       errResponse.Type,
+		Title:      errResponse.Title,
+		LogID:      errResponse.LogID,
+		Details:    errResponse.Details,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Body:       body,
+	}
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as HTTP-date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package kis
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "60", 60 * time.Second},
+		{"seconds with spaces", " 5 ", 5 * time.Second},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-5", 0},
+		{"HTTP-date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"RFC 850 date", now.Add(time.Hour).Format(time.RFC850), time.Hour},
+		{"past HTTP-date", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"current HTTP-date", now.Format(http.TimeFormat), 0},
+		{"fractional seconds", "1.5", 0},
+		{"garbage", "soon", 0},
+		{"ISO 8601 date", "2024-05-01T12:01:00Z", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package kis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// executor sends the requests of a Kubota client and its authentication.
+type executor struct {
+	doer      Doer
+	userAgent string
+	retry     RetryPolicy
+
+	limiter          *limiter
+	endpointLimiters map[string]*limiter
+	onRateLimitWait  func(endpoint string, wait time.Duration)
+	logger           *slog.Logger
+	metrics          MetricsCollector
+}
+
+// do sends the request created by newReq and retries it according to the retry policy.
+// newReq is called for every attempt, so request bodies can be recreated.
+// The last response is returned as is, independent of its status code.
+func (e *executor) do(ctx context.Context, info RequestInfo, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := e.waitRateLimit(ctx, info.Endpoint); err != nil {
+			return nil, err
+		}
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		if e.userAgent != "" {
+			req.Header.Set("User-Agent", e.userAgent)
+		}
+		info.Attempt = attempt
+		req = req.WithContext(context.WithValue(req.Context(), requestInfoKey{}, info))
+		start := time.Now()
+		resp, err := e.doer.Do(req)
+		if err != nil {
+			e.metrics.ObserveRequest(info.Endpoint, 0, time.Since(start))
+		} else {
+			e.metrics.ObserveRequest(info.Endpoint, resp.StatusCode, time.Since(start))
+		}
+		last := attempt >= e.retry.MaxAttempts
+		var wait time.Duration
+		switch {
+		case err != nil:
+			// errors caused by the context of the caller are final
+			if last || ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			wait = e.retry.backoff(attempt)
+			e.logger.LogAttrs(ctx, slog.LevelWarn, "retrying request after error",
+				slog.String("endpoint", info.Endpoint), slog.Int("attempt", attempt), slog.Duration("wait", wait), slog.Any("error", err))
+		case retryableStatus(resp.StatusCode) && !last:
+			wait = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if wait == 0 {
+				wait = e.retry.backoff(attempt)
+			} else if e.retry.MaxRetryAfter > 0 && wait > e.retry.MaxRetryAfter {
+				return resp, nil
+			}
+			e.logger.LogAttrs(ctx, slog.LevelWarn, "retrying request after status",
+				slog.String("endpoint", info.Endpoint), slog.Int("attempt", attempt), slog.Duration("wait", wait), slog.Int("status", resp.StatusCode))
+			// drain the body to allow the connection to be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+		e.metrics.ObserveRetry(info.Endpoint)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitRateLimit blocks until the global and the endpoint rate limiter allow another request.
+func (e *executor) waitRateLimit(ctx context.Context, endpoint string) error {
+	waited, err := e.limiter.wait(ctx)
+	if err == nil {
+		var d time.Duration
+		d, err = e.endpointLimiters[endpoint].wait(ctx)
+		waited += d
+	}
+	if waited > 0 {
+		e.logger.LogAttrs(ctx, slog.LevelDebug, "request delayed by rate limiter",
+			slog.String("endpoint", endpoint), slog.Duration("wait", waited))
+		e.metrics.ObserveRateLimitWait(endpoint, waited)
+		if e.onRateLimitWait != nil {
+			e.onRateLimitWait(endpoint, waited)
+		}
+	}
+	return err
+}
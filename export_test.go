@@ -0,0 +1,11 @@
+package kis
+
+import "time"
+
+// SetTokenRequestTimeout changes the timeout of token refreshes for a test and returns a function
+// restoring it.
+func SetTokenRequestTimeout(d time.Duration) (restore func()) {
+	old := tokenRequestTimeout
+	tokenRequestTimeout = d
+	return func() { tokenRequestTimeout = old }
+}
@@ -1,11 +1,6 @@
 package kis
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
-)
+import "context"
 
 // Field represents the Field information returned by the Kubota API.
 type Field struct {
@@ -27,60 +22,36 @@ type Shape struct {
 
 // GetFieldByMobilePhone retrieves field information by mobile phone number.
 func (k *Kubota) GetFieldByMobilePhone(mobilePhone string) ([]Field, error) {
-	return k.getField("mobilePhone", mobilePhone)
+	return payload(k.getField(context.Background(), "mobilePhone", mobilePhone))
 }
 
-// GetFieldByUserName retrieves field information by username.
-func (k *Kubota) GetFieldByUserName(userName string) ([]Field, error) {
-	return k.getField("userName", userName)
+// GetFieldByMobilePhoneContext is like GetFieldByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetFieldByMobilePhoneContext(ctx context.Context, mobilePhone string) ([]Field, error) {
+	return payload(k.getField(ctx, "mobilePhone", mobilePhone))
 }
 
-// getField is a helper function to retrieve field information based on a given field.
-func (k *Kubota) getField(f, value string) ([]Field, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/field?%s=%s", k.authentication.Endpoint, f, value)
-
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating field request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
+// GetFieldByMobilePhoneWithMeta is like GetFieldByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetFieldByMobilePhoneWithMeta(ctx context.Context, mobilePhone string) ([]Field, ResponseMeta, error) {
+	return k.getField(ctx, "mobilePhone", mobilePhone)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making field request: %w", err)
-	}
-	defer resp.Body.Close()
+// GetFieldByUserName retrieves field information by username.
+func (k *Kubota) GetFieldByUserName(userName string) ([]Field, error) {
+	return payload(k.getField(context.Background(), "userName", userName))
+}
 
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return nil, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return nil, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
+// GetFieldByUserNameContext is like GetFieldByUserName but uses ctx to bound the request.
+func (k *Kubota) GetFieldByUserNameContext(ctx context.Context, userName string) ([]Field, error) {
+	return payload(k.getField(ctx, "userName", userName))
+}
 
-	// Unmarshal the response
-	var fieldResponse struct {
-		Status   int     `json:"Status"`
-		Resource string  `json:"Resource"`
-		Payload  []Field `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&fieldResponse); err != nil {
-		return nil, fmt.Errorf("error decoding field response: %w", err)
-	}
+// GetFieldByUserNameWithMeta is like GetFieldByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetFieldByUserNameWithMeta(ctx context.Context, userName string) ([]Field, ResponseMeta, error) {
+	return k.getField(ctx, "userName", userName)
+}
 
-	// Return the field information
-	return fieldResponse.Payload, nil
+// getField is a helper function to retrieve field information based on a given field.
+func (k *Kubota) getField(ctx context.Context, f, value string) ([]Field, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetField", Endpoint: "field", Field: f}
+	return do[[]Field](ctx, k, call, value)
 }
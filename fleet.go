@@ -0,0 +1,148 @@
+package kis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultFleetConcurrency is the number of machines fetched in parallel if FleetOptions.Concurrency is not set.
+const DefaultFleetConcurrency = 4
+
+// FleetOptions configures the fleet getters.
+type FleetOptions struct {
+	// Concurrency is the number of machines fetched in parallel, DefaultFleetConcurrency if zero.
+	// All requests pass the rate limiters of the client, so a higher concurrency does not cause more 429s
+	// than the configured rate allows.
+	Concurrency int
+}
+
+// FleetResult holds the per-machine results of a fleet getter.
+type FleetResult[T any] struct {
+	// MachineUUIDs are the requested machines in the order given, without duplicates.
+	MachineUUIDs []string
+	// Values holds the result of every machine that succeeded.
+	Values map[string]T
+	// Errors holds the error of every machine that failed.
+	Errors map[string]error
+}
+
+// Err returns a *FleetError listing the failed machines in request order, or nil if all succeeded.
+func (r *FleetResult[T]) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	fleetErr := &FleetError{}
+	for _, id := range r.MachineUUIDs {
+		if err, ok := r.Errors[id]; ok {
+			fleetErr.Machines = append(fleetErr.Machines, &MachineError{MachineUUID: id, Err: err})
+		}
+	}
+	return fleetErr
+}
+
+// MachineError is the error of a single machine of a fleet getter.
+type MachineError struct {
+	MachineUUID string
+	Err         error
+}
+
+// Error implements the error interface.
+func (e *MachineError) Error() string {
+	return fmt.Sprintf("machine %s: %s", e.MachineUUID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *MachineError) Unwrap() error {
+	return e.Err
+}
+
+// FleetError is returned by the fleet getters if some machines failed. The results of the
+// other machines are returned along with it.
+type FleetError struct {
+	Machines []*MachineError
+}
+
+// Error implements the error interface.
+func (e *FleetError) Error() string {
+	msgs := make([]string, len(e.Machines))
+	for i, m := range e.Machines {
+		msgs[i] = m.Error()
+	}
+	return fmt.Sprintf("error for %d machines: %s", len(e.Machines), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed machines, so errors.Is and errors.As match any of them.
+func (e *FleetError) Unwrap() []error {
+	errs := make([]error, len(e.Machines))
+	for i, m := range e.Machines {
+		errs[i] = m
+	}
+	return errs
+}
+
+// GetLastPositions retrieves the last position of every machine. If some machines fail, the result
+// holds the positions of the others and a *FleetError is returned along with it.
+func (k *Kubota) GetLastPositions(ctx context.Context, machineUUIDs []string, subscription string, opts FleetOptions) (*FleetResult[*Position], error) {
+	return fleet(ctx, machineUUIDs, opts, func(ctx context.Context, id string) (*Position, error) {
+		return payload(k.getPosition(ctx, "machineUUID", id, subscription))
+	})
+}
+
+// GetHistoricalPositions retrieves the historical positions of every machine. If some machines fail, the
+// result holds the positions of the others and a *FleetError is returned along with it.
+func (k *Kubota) GetHistoricalPositions(ctx context.Context, machineUUIDs []string, subscription string, startDate, endDate time.Time, opts FleetOptions) (*FleetResult[[]Position], error) {
+	return fleet(ctx, machineUUIDs, opts, func(ctx context.Context, id string) ([]Position, error) {
+		return payload(k.getPositions(ctx, "machineUUID", id, subscription, startDate, endDate))
+	})
+}
+
+// GetHistoricalMeasures retrieves the historical measures of every machine. If some machines fail, the
+// result holds the measures of the others and a *FleetError is returned along with it.
+func (k *Kubota) GetHistoricalMeasures(ctx context.Context, machineUUIDs []string, subscription string, startDate, endDate time.Time, opts FleetOptions) (*FleetResult[[]Measure], error) {
+	return fleet(ctx, machineUUIDs, opts, func(ctx context.Context, id string) ([]Measure, error) {
+		return payload(k.getMeasure(ctx, "machineUUID", id, subscription, startDate, endDate))
+	})
+}
+
+// GetHistoricalAlarms retrieves the historical alarms of every machine. If some machines fail, the
+// result holds the alarms of the others and a *FleetError is returned along with it.
+func (k *Kubota) GetHistoricalAlarms(ctx context.Context, machineUUIDs []string, subscription string, startDate, endDate time.Time, opts FleetOptions) (*FleetResult[[]Alarm], error) {
+	return fleet(ctx, machineUUIDs, opts, func(ctx context.Context, id string) ([]Alarm, error) {
+		return payload(k.getAlarm(ctx, "machineUUID", id, subscription, startDate, endDate))
+	})
+}
+
+// fleet fetches every machine with bounded concurrency. Machines are started in request order;
+// once ctx is done, the remaining ones fail with its error without being requested.
+func fleet[T any](ctx context.Context, machineUUIDs []string, opts FleetOptions, fetch func(ctx context.Context, machineUUID string) (T, error)) (*FleetResult[T], error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultFleetConcurrency
+	}
+	r := &FleetResult[T]{
+		Values: make(map[string]T),
+		Errors: make(map[string]error),
+	}
+	seen := make(map[string]bool)
+	for _, id := range machineUUIDs {
+		if !seen[id] {
+			seen[id] = true
+			r.MachineUUIDs = append(r.MachineUUIDs, id)
+		}
+	}
+
+	values := make([]T, len(r.MachineUUIDs))
+	errs := parallel(ctx, len(r.MachineUUIDs), opts.Concurrency, func(ctx context.Context, i int) (err error) {
+		values[i], err = fetch(ctx, r.MachineUUIDs[i])
+		return err
+	})
+	for i, id := range r.MachineUUIDs {
+		if errs[i] != nil {
+			r.Errors[id] = errs[i]
+			continue
+		}
+		r.Values[id] = values[i]
+	}
+	return r, r.Err()
+}
@@ -0,0 +1,113 @@
+package kis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFleetDuplicatesAndOrder(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	fetch := func(ctx context.Context, id string) (string, error) {
+		mu.Lock()
+		calls[id]++
+		mu.Unlock()
+		return "value of " + id, nil
+	}
+	r, err := fleet(context.Background(), []string{"c", "a", "c", "b", "a"}, FleetOptions{Concurrency: 2}, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(r.MachineUUIDs, want) {
+		t.Errorf("MachineUUIDs = %v, want %v", r.MachineUUIDs, want)
+	}
+	if want := map[string]int{"a": 1, "b": 1, "c": 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want every machine fetched once", calls)
+	}
+	if len(r.Values) != 3 || r.Values["b"] != "value of b" {
+		t.Errorf("Values = %v, want the values of a, b and c", r.Values)
+	}
+	if len(r.Errors) != 0 || r.Err() != nil {
+		t.Errorf("Errors = %v, want none", r.Errors)
+	}
+}
+
+func TestFleetErrors(t *testing.T) {
+	errDown := errors.New("down")
+	fetch := func(ctx context.Context, id string) (int, error) {
+		if id == "d" || id == "b" {
+			return 0, errDown
+		}
+		return len(id), nil
+	}
+	r, err := fleet(context.Background(), []string{"d", "a", "b", "c"}, FleetOptions{}, fetch)
+	var fleetErr *FleetError
+	if !errors.As(err, &fleetErr) || !errors.Is(err, errDown) {
+		t.Fatalf("error = %v, want *FleetError wrapping the machine errors", err)
+	}
+	var failed []string
+	for _, m := range fleetErr.Machines {
+		failed = append(failed, m.MachineUUID)
+	}
+	if want := []string{"d", "b"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed machines = %v, want %v in request order", failed, want)
+	}
+	if want := map[string]error{"d": errDown, "b": errDown}; !reflect.DeepEqual(r.Errors, want) {
+		t.Errorf("Errors = %v, want %v", r.Errors, want)
+	}
+	if want := map[string]int{"a": 1, "c": 1}; !reflect.DeepEqual(r.Values, want) {
+		t.Errorf("Values = %v, want %v", r.Values, want)
+	}
+}
+
+func TestFleetCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, id string) (string, error) {
+		if calls.Add(1) == 2 {
+			cancel()
+			return "", ctx.Err()
+		}
+		return id, nil
+	}
+	r, err := fleet(ctx, []string{"a", "b", "c", "d"}, FleetOptions{Concurrency: 1}, fetch)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fetched %d machines, want 2", n)
+	}
+	if len(r.Values) != 1 || r.Values["a"] != "a" {
+		t.Errorf("Values = %v, want only a", r.Values)
+	}
+	for _, id := range []string{"b", "c", "d"} {
+		if !errors.Is(r.Errors[id], context.Canceled) {
+			t.Errorf("Errors[%s] = %v, want context.Canceled", id, r.Errors[id])
+		}
+	}
+}
+
+func TestFleetConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	fetch := func(ctx context.Context, id string) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		return id, nil
+	}
+	ids := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	if _, err := fleet(context.Background(), ids, FleetOptions{Concurrency: 3}, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if p := peak.Load(); p != 3 {
+		t.Errorf("%d machines fetched in parallel, want 3", p)
+	}
+}
@@ -0,0 +1,150 @@
+// Package geofence detects when machines enter, stay on and leave fields.
+package geofence
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+)
+
+// EventType is the kind of an Event.
+type EventType int
+
+const (
+	// Enter is emitted when a machine arrives on a field.
+	Enter EventType = iota
+	// Exit is emitted when a machine leaves a field.
+	Exit
+	// Dwell is emitted once per visit when a machine has been on a field for Options.DwellTime.
+	Dwell
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case Exit:
+		return "exit"
+	case Dwell:
+		return "dwell"
+	default:
+		return "enter"
+	}
+}
+
+// Event is a change of the field a machine is on.
+type Event struct {
+	Type        EventType
+	MachineUUID string
+	FieldID     string
+	// Time is the Timestamp of the position that caused the event.
+	Time time.Time
+	// Entered is the Timestamp of the first position on the field.
+	Entered time.Time
+	// Duration is the time between Entered and Time, zero for Enter events.
+	Duration  time.Duration
+	Latitude  float64
+	Longitude float64
+}
+
+// Options configures an Engine.
+type Options struct {
+	// DwellTime is the time a machine has to stay on a field before a Dwell event is emitted. No Dwell
+	// events are emitted if zero.
+	DwellTime time.Duration
+}
+
+// Engine tracks the field every machine is on and emits an Event on every change. It is safe for
+// concurrent use.
+type Engine struct {
+	ix   *kis.FieldIndex
+	opts Options
+
+	mu       sync.Mutex
+	machines map[string]*visit
+}
+
+// visit is the state of one machine.
+type visit struct {
+	fieldID string
+	entered time.Time
+	last    time.Time
+	dwelt   bool
+}
+
+// NewEngine returns an Engine for the fields. If fields overlap, the one given first wins.
+func NewEngine(fields []kis.Field, opts Options) *Engine {
+	return &Engine{
+		ix:       kis.NewFieldIndex(fields),
+		opts:     opts,
+		machines: make(map[string]*visit),
+	}
+}
+
+// Update feeds positions to the engine and returns the resulting events in order. Positions of a
+// machine which are not newer than the last one seen are ignored, so the last position of a machine
+// can be polled repeatedly. A machine first seen on a field enters it with that position.
+func (e *Engine) Update(positions ...kis.Position) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var events []Event
+	for _, p := range positions {
+		events = append(events, e.update(p)...)
+	}
+	return events
+}
+
+func (e *Engine) update(p kis.Position) []Event {
+	t := p.Timestamp.Time
+	v, ok := e.machines[p.MachineUUID]
+	if !ok {
+		v = &visit{}
+		e.machines[p.MachineUUID] = v
+	} else if !t.After(v.last) {
+		return nil
+	}
+	v.last = t
+
+	fieldID := ""
+	if f, ok := e.ix.Lookup(p.Latitude, p.Longitude); ok {
+		fieldID = f.FieldID
+	}
+	var events []Event
+	if fieldID != v.fieldID {
+		if v.fieldID != "" {
+			events = append(events, e.event(Exit, p, v))
+		}
+		v.fieldID, v.entered, v.dwelt = fieldID, t, false
+		if fieldID != "" {
+			events = append(events, e.event(Enter, p, v))
+		}
+	}
+	if v.fieldID != "" && !v.dwelt && e.opts.DwellTime > 0 && t.Sub(v.entered) >= e.opts.DwellTime {
+		v.dwelt = true
+		events = append(events, e.event(Dwell, p, v))
+	}
+	return events
+}
+
+func (e *Engine) event(typ EventType, p kis.Position, v *visit) Event {
+	return Event{
+		Type:        typ,
+		MachineUUID: p.MachineUUID,
+		FieldID:     v.fieldID,
+		Time:        p.Timestamp.Time,
+		Entered:     v.entered,
+		Duration:    p.Timestamp.Sub(v.entered),
+		Latitude:    p.Latitude,
+		Longitude:   p.Longitude,
+	}
+}
+
+// Detect returns the events of historical positions, sorted by Time. The input slice is not modified.
+// Machines still on a field after their last position have no Exit event.
+func Detect(positions []kis.Position, fields []kis.Field, opts Options) []Event {
+	sorted := make([]kis.Position, len(positions))
+	copy(sorted, positions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp.Time) })
+	return NewEngine(fields, opts).Update(sorted...)
+}
@@ -0,0 +1,162 @@
+package geofence
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+)
+
+var start = time.Date(2024, 7, 1, 6, 0, 0, 0, time.UTC)
+
+// fields are two adjacent squares of 0.01°: a from 10° to 10.01° east and b from 10.01° to 10.02° east.
+var fields = []kis.Field{
+	{FieldID: "a", Shape: kis.Shape{Type: "Polygon", Coordinates: [][][2]float64{{{10, 50}, {10.01, 50}, {10.01, 50.01}, {10, 50.01}, {10, 50}}}}},
+	{FieldID: "b", Shape: kis.Shape{Type: "Polygon", Coordinates: [][][2]float64{{{10.01, 50}, {10.02, 50}, {10.02, 50.01}, {10.01, 50.01}, {10.01, 50}}}}},
+}
+
+// Longitudes on a, on b and outside both fields.
+const (
+	onA     = 10.005
+	onB     = 10.015
+	outside = 10.03
+)
+
+func at(machineUUID string, minute int, lon float64) kis.Position {
+	return kis.Position{
+		MachineUUID: machineUUID,
+		Latitude:    50.005,
+		Longitude:   lon,
+		Timestamp:   kis.CustomTime{Time: start.Add(time.Duration(minute) * time.Minute)},
+	}
+}
+
+// describe returns the events as "machine type field minute duration" strings.
+func describe(events []Event) []string {
+	var s []string
+	for _, e := range events {
+		s = append(s, fmt.Sprintf("%s %s %s %v %v", e.MachineUUID, e.Type, e.FieldID, e.Time.Sub(start).Minutes(), e.Duration))
+	}
+	return s
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions []kis.Position
+		opts      Options
+		want      []string
+	}{
+		{
+			"enter and exit",
+			[]kis.Position{at("m1", 0, outside), at("m1", 1, onA), at("m1", 2, onA), at("m1", 3, outside)},
+			Options{},
+			[]string{"m1 enter a 1 0s", "m1 exit a 3 2m0s"},
+		},
+		{
+			"enter, dwell and exit",
+			[]kis.Position{at("m1", 0, onA), at("m1", 4, onA), at("m1", 5, onA), at("m1", 9, onA), at("m1", 10, outside)},
+			Options{DwellTime: 5 * time.Minute},
+			[]string{"m1 enter a 0 0s", "m1 dwell a 5 5m0s", "m1 exit a 10 10m0s"},
+		},
+		{
+			"dwell once per visit",
+			[]kis.Position{at("m1", 0, onA), at("m1", 6, onA), at("m1", 7, outside), at("m1", 8, onA), at("m1", 9, onA), at("m1", 20, onA)},
+			Options{DwellTime: 5 * time.Minute},
+			[]string{"m1 enter a 0 0s", "m1 dwell a 6 6m0s", "m1 exit a 7 7m0s", "m1 enter a 8 0s", "m1 dwell a 20 12m0s"},
+		},
+		{
+			"visit shorter than DwellTime",
+			[]kis.Position{at("m1", 0, onA), at("m1", 4, onA), at("m1", 5, outside)},
+			Options{DwellTime: 5 * time.Minute},
+			[]string{"m1 enter a 0 0s", "m1 exit a 5 5m0s"},
+		},
+		{
+			"from field to field",
+			[]kis.Position{at("m1", 0, onA), at("m1", 1, onB), at("m1", 2, outside)},
+			Options{},
+			[]string{"m1 enter a 0 0s", "m1 exit a 1 1m0s", "m1 enter b 1 0s", "m1 exit b 2 1m0s"},
+		},
+		{
+			"still on the field at the end",
+			[]kis.Position{at("m1", 0, outside), at("m1", 1, onA)},
+			Options{},
+			[]string{"m1 enter a 1 0s"},
+		},
+		{
+			"unsorted machines",
+			[]kis.Position{at("m2", 3, outside), at("m1", 2, outside), at("m2", 1, onB), at("m1", 0, onA), at("m2", 2, onA)},
+			Options{},
+			[]string{"m1 enter a 0 0s", "m2 enter b 1 0s", "m1 exit a 2 2m0s", "m2 exit b 2 1m0s", "m2 enter a 2 0s", "m2 exit a 3 1m0s"},
+		},
+		{
+			"repeated positions",
+			[]kis.Position{at("m1", 0, onA), at("m1", 0, onB), at("m1", 1, onA)},
+			Options{},
+			[]string{"m1 enter a 0 0s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]kis.Position(nil), tt.positions...)
+			got := describe(Detect(tt.positions, fields, tt.opts))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.positions, input) {
+				t.Error("Detect() modified the input")
+			}
+		})
+	}
+}
+
+func TestEngineUpdatePolling(t *testing.T) {
+	e := NewEngine(fields, Options{DwellTime: 5 * time.Minute})
+	steps := []struct {
+		name     string
+		position kis.Position
+		want     []string
+	}{
+		{"first position on a field", at("m1", 0, onA), []string{"m1 enter a 0 0s"}},
+		{"same position polled again", at("m1", 0, onA), nil},
+		{"stale position elsewhere", at("m1", -1, onB), nil},
+		{"same time elsewhere", at("m1", 0, outside), nil},
+		{"still on the field", at("m1", 3, onA), nil},
+		{"other machine", at("m2", 3, onB), []string{"m2 enter b 3 0s"}},
+		{"dwell", at("m1", 5, onA), []string{"m1 dwell a 5 5m0s"}},
+		{"dwell polled again", at("m1", 5, onA), nil},
+		{"no second dwell", at("m1", 12, onA), nil},
+		{"to the other field", at("m1", 13, onB), []string{"m1 exit a 13 13m0s", "m1 enter b 13 0s"}},
+		{"left", at("m1", 14, outside), []string{"m1 exit b 14 1m0s"}},
+		{"left polled again", at("m1", 14, outside), nil},
+	}
+	for _, s := range steps {
+		if got := describe(e.Update(s.position)); !reflect.DeepEqual(got, s.want) {
+			t.Errorf("%s: Update() = %q, want %q", s.name, got, s.want)
+		}
+	}
+}
+
+func TestEngineUpdateEventFields(t *testing.T) {
+	e := NewEngine(fields, Options{})
+	e.Update(at("m1", 0, onA))
+	events := e.Update(at("m1", 7, outside))
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	want := Event{
+		Type:        Exit,
+		MachineUUID: "m1",
+		FieldID:     "a",
+		Time:        start.Add(7 * time.Minute),
+		Entered:     start,
+		Duration:    7 * time.Minute,
+		Latitude:    50.005,
+		Longitude:   outside,
+	}
+	if events[0] != want {
+		t.Errorf("event = %+v, want %+v", events[0], want)
+	}
+}
@@ -0,0 +1,245 @@
+package kis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/maltegrosse/go-kubota-kis-api/internal/geo"
+)
+
+// Problems reported by Shape.Validate, wrapped with the index of the ring.
+var (
+	// ErrEmptyShape is returned for a shape without rings.
+	ErrEmptyShape = errors.New("kis: shape has no rings")
+	// ErrRingTooShort is returned for a ring with fewer than three distinct points.
+	ErrRingTooShort = errors.New("kis: ring has fewer than three distinct points")
+	// ErrRingNotClosed is returned for a ring whose last point differs from the first one.
+	ErrRingNotClosed = errors.New("kis: ring is not closed")
+	// ErrWindingOrder is returned for an outer ring running clockwise or a hole running counterclockwise.
+	ErrWindingOrder = errors.New("kis: wrong winding order")
+	// ErrSelfIntersection is returned for edges crossing or touching each other.
+	ErrSelfIntersection = errors.New("kis: self-intersection")
+)
+
+// Area returns the geodesic area of the field in square meters.
+func (f Field) Area() float64 {
+	return f.Shape.Area()
+}
+
+// AreaHectares returns the geodesic area of the field in hectares.
+func (f Field) AreaHectares() float64 {
+	return f.Shape.AreaHectares()
+}
+
+// Area returns the geodesic area of the shape in square meters. Holes are subtracted, following the
+// even-odd rule of Contains.
+func (s Shape) Area() float64 {
+	rings := s.rings()
+	var area float64
+	for i, r := range rings {
+		if len(r) < 3 {
+			continue
+		}
+		a := math.Abs(geo.RingArea(r))
+		if depth(rings, i)%2 == 1 {
+			a = -a
+		}
+		area += a
+	}
+	return area
+}
+
+// AreaHectares returns the geodesic area of the shape in hectares.
+func (s Shape) AreaHectares() float64 {
+	return s.Area() / 10000
+}
+
+// Perimeter returns the geodesic length of all rings in meters, including those of holes.
+func (s Shape) Perimeter() float64 {
+	var length float64
+	for _, r := range s.rings() {
+		if len(r) < 2 {
+			continue
+		}
+		for i, a := range r {
+			b := r[(i+1)%len(r)]
+			length += geo.Distance(a[1], a[0], b[1], b[0])
+		}
+	}
+	return length
+}
+
+// Centroid returns the center of mass of the shape in degrees, with holes subtracted. It is computed in
+// the plane of the coordinates, which is accurate for shapes of the size of a field. The centroid of a
+// concave shape or one with several parts may lie outside of it. ok is false if the shape has no area.
+func (s Shape) Centroid() (latitude, longitude float64, ok bool) {
+	rings := s.rings()
+	var sum, sumLat, sumLon float64
+	for i, r := range rings {
+		if len(r) < 3 {
+			continue
+		}
+		a, lat, lon := planarCentroid(r)
+		w := math.Abs(a)
+		if depth(rings, i)%2 == 1 {
+			w = -w
+		}
+		sum += w
+		sumLat += w * lat
+		sumLon += w * lon
+	}
+	if sum == 0 {
+		return 0, 0, false
+	}
+	return sumLat / sum, sumLon / sum, true
+}
+
+// Validate checks that the shape has rings, that every ring is closed and has at least three distinct
+// points, that outer rings run counterclockwise and holes clockwise as required by RFC 7946, and that no
+// edges cross or touch apart from neighbouring edges sharing a point. All problems are joined into the
+// returned error and can be matched with errors.Is.
+func (s Shape) Validate() error {
+	if len(s.Coordinates) == 0 {
+		return fmt.Errorf("error validating shape: %w", ErrEmptyShape)
+	}
+	rings := s.rings()
+	var errs []error
+	for i, r := range s.Coordinates {
+		if len(rings[i]) < 3 {
+			errs = append(errs, fmt.Errorf("error in ring %d: %w", i, ErrRingTooShort))
+			continue
+		}
+		if r[0] != r[len(r)-1] {
+			errs = append(errs, fmt.Errorf("error in ring %d: %w", i, ErrRingNotClosed))
+		}
+		a, _, _ := planarCentroid(rings[i])
+		if (a > 0) != (depth(rings, i)%2 == 0) {
+			errs = append(errs, fmt.Errorf("error in ring %d: %w", i, ErrWindingOrder))
+		}
+	}
+	if err := intersection(rings); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// rings returns the rings of the shape without repeated points and without the closing point.
+func (s Shape) rings() [][][2]float64 {
+	rings := make([][][2]float64, len(s.Coordinates))
+	for i, r := range s.Coordinates {
+		var ring [][2]float64
+		for _, c := range r {
+			if len(ring) == 0 || c != ring[len(ring)-1] {
+				ring = append(ring, c)
+			}
+		}
+		if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+			ring = ring[:len(ring)-1]
+		}
+		rings[i] = ring
+	}
+	return rings
+}
+
+// depth returns the number of other rings containing ring i. Rings at an even depth are outer rings,
+// those at an odd depth are holes.
+func depth(rings [][][2]float64, i int) int {
+	if len(rings[i]) == 0 {
+		return 0
+	}
+	p := rings[i][0]
+	n := 0
+	for j, r := range rings {
+		if j != i && ringContains(r, p[1], p[0]) {
+			n++
+		}
+	}
+	return n
+}
+
+// planarCentroid returns the signed area, positive for counterclockwise rings, and the centroid of the
+// ring in the plane of the coordinates. The coordinates are taken relative to the first point to limit
+// rounding errors.
+func planarCentroid(ring [][2]float64) (area, latitude, longitude float64) {
+	o := ring[0]
+	var cx, cy float64
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		ax, ay, bx, by := a[0]-o[0], a[1]-o[1], b[0]-o[0], b[1]-o[1]
+		cross := ax*by - bx*ay
+		area += cross
+		cx += (ax + bx) * cross
+		cy += (ay + by) * cross
+	}
+	area /= 2
+	if area == 0 {
+		return 0, o[1], o[0]
+	}
+	return area, cy/(6*area) + o[1], cx/(6*area) + o[0]
+}
+
+// intersection returns an error for the first pair of edges which cross or touch, ignoring the shared
+// point of neighbouring edges of the same ring.
+func intersection(rings [][][2]float64) error {
+	for i, r := range rings {
+		if len(r) < 3 {
+			continue
+		}
+		for a := range r {
+			for j := i; j < len(rings); j++ {
+				s := rings[j]
+				if len(s) < 3 {
+					continue
+				}
+				b := 0
+				if j == i {
+					b = a + 1
+				}
+				for ; b < len(s); b++ {
+					if j == i && (b == a+1 || (a == 0 && b == len(r)-1)) {
+						continue
+					}
+					if !segmentsIntersect(r[a], r[(a+1)%len(r)], s[b], s[(b+1)%len(s)]) {
+						continue
+					}
+					if j == i {
+						return fmt.Errorf("error in ring %d: %w of the edges starting at %v and %v", i, ErrSelfIntersection, r[a], s[b])
+					}
+					return fmt.Errorf("error in rings %d and %d: %w of the edges starting at %v and %v", i, j, ErrSelfIntersection, r[a], s[b])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// segmentsIntersect reports whether the segments p1-p2 and q1-q2 have a point in common.
+func segmentsIntersect(p1, p2, q1, q2 [2]float64) bool {
+	d1, d2 := orientation(q1, q2, p1), orientation(q1, q2, p2)
+	d3, d4 := orientation(p1, p2, q1), orientation(p1, p2, q2)
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation returns the sign of the turn from a-b to a-c: positive for counterclockwise, negative
+// for clockwise and zero if the points are collinear.
+func orientation(a, b, c [2]float64) float64 {
+	v := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// onSegment reports whether c, collinear with a and b, lies between them.
+func onSegment(a, b, c [2]float64) bool {
+	return math.Min(a[0], b[0]) <= c[0] && c[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= c[1] && c[1] <= math.Max(a[1], b[1])
+}
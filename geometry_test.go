@@ -0,0 +1,152 @@
+package kis
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// square returns a closed counterclockwise ring with the south-west corner at lon, lat.
+func square(lon, lat, size float64) [][2]float64 {
+	return [][2]float64{{lon, lat}, {lon + size, lat}, {lon + size, lat + size}, {lon, lat + size}, {lon, lat}}
+}
+
+// reversed returns the ring with the opposite winding order.
+func reversed(ring [][2]float64) [][2]float64 {
+	r := make([][2]float64, len(ring))
+	for i, c := range ring {
+		r[len(ring)-1-i] = c
+	}
+	return r
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestShapeSquareAtEquator(t *testing.T) {
+	// 100 m on the WGS84 equator.
+	s := Shape{Type: "Polygon", Coordinates: [][][2]float64{square(0, 0, 0.000898315)}}
+	if ha := s.AreaHectares(); !near(ha, 0.9978, 0.0001) {
+		t.Errorf("AreaHectares() = %f, want 0.9978", ha)
+	}
+	if a := s.Area(); !near(a, 9978, 1) {
+		t.Errorf("Area() = %f, want 9978", a)
+	}
+	if p := s.Perimeter(); !near(p, 399.55, 0.05) {
+		t.Errorf("Perimeter() = %f, want 399.55", p)
+	}
+	lat, lon, ok := s.Centroid()
+	if !ok || !near(lat, 0.000449158, 1e-9) || !near(lon, 0.000449158, 1e-9) {
+		t.Errorf("Centroid() = %f, %f, %v, want 0.000449, 0.000449, true", lat, lon, ok)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestShapeCentralPark(t *testing.T) {
+	// The corners of Central Park, New York, at 59th and 110th Street. Its published area is 843 acres (341.2 ha).
+	f := Field{FieldName: "Central Park", Shape: Shape{Type: "Polygon", Coordinates: [][][2]float64{{
+		{-73.9730, 40.7644}, {-73.9496, 40.7968}, {-73.9582, 40.8003}, {-73.9819, 40.7681}, {-73.9730, 40.7644},
+	}}}}
+	if ha := f.AreaHectares(); !near(ha, 341.2, 341.2*0.015) {
+		t.Errorf("AreaHectares() = %f, want 341.2 within 1.5%%", ha)
+	}
+	if f.Area() != f.Shape.Area() {
+		t.Errorf("Field.Area() = %f, want Shape.Area() = %f", f.Area(), f.Shape.Area())
+	}
+	// The park is 4 km long and 0.8 km wide.
+	if p := f.Shape.Perimeter(); !near(p, 9800, 150) {
+		t.Errorf("Perimeter() = %f, want about 9800", p)
+	}
+	want := BoundingBox{MinLatitude: 40.7644, MinLongitude: -73.9819, MaxLatitude: 40.8003, MaxLongitude: -73.9496}
+	if b := f.Shape.BoundingBox(); b != want {
+		t.Errorf("BoundingBox() = %+v, want %+v", b, want)
+	}
+	if err := f.Shape.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestShapeWithHole(t *testing.T) {
+	outer := square(10, 10, 0.01)
+	hole := reversed(square(10.006, 10.006, 0.002))
+	s := Shape{Type: "Polygon", Coordinates: [][][2]float64{outer, hole}}
+
+	want := Shape{Coordinates: [][][2]float64{outer}}.Area() - Shape{Coordinates: [][][2]float64{hole}}.Area()
+	if a := s.Area(); !near(a, want, 1e-6) {
+		t.Errorf("Area() = %f, want %f", a, want)
+	}
+	// The hole in the north-east shifts the centroid south-west of the center at 10.005.
+	c := 10.005 - 0.002*0.002*0.002/(0.01*0.01-0.002*0.002)
+	lat, lon, ok := s.Centroid()
+	if !ok || !near(lat, c, 1e-9) || !near(lon, c, 1e-9) {
+		t.Errorf("Centroid() = %.9f, %.9f, %v, want %.9f, %.9f, true", lat, lon, ok, c, c)
+	}
+	// The hole counts towards the perimeter.
+	per := Shape{Coordinates: [][][2]float64{outer}}.Perimeter() + Shape{Coordinates: [][][2]float64{hole}}.Perimeter()
+	if p := s.Perimeter(); !near(p, per, 1e-6) {
+		t.Errorf("Perimeter() = %f, want %f", p, per)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestShapeSeveralParts(t *testing.T) {
+	a := Shape{Coordinates: [][][2]float64{square(0, 0, 0.001)}}
+	b := Shape{Coordinates: [][][2]float64{square(0.002, 0, 0.001)}}
+	s := Shape{Coordinates: [][][2]float64{a.Coordinates[0], b.Coordinates[0]}}
+	if got, want := s.Area(), a.Area()+b.Area(); !near(got, want, 1e-6) {
+		t.Errorf("Area() = %f, want %f", got, want)
+	}
+	if _, lon, _ := s.Centroid(); !near(lon, 0.0015, 1e-9) {
+		t.Errorf("Centroid() longitude = %f, want 0.0015", lon)
+	}
+}
+
+func TestShapeValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords [][][2]float64
+		want   []error
+	}{
+		{"clockwise outer ring", [][][2]float64{reversed(square(0, 0, 1))}, []error{ErrWindingOrder}},
+		{"counterclockwise hole", [][][2]float64{square(0, 0, 1), square(0.4, 0.4, 0.2)}, []error{ErrWindingOrder}},
+		{"open ring", [][][2]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}, []error{ErrRingNotClosed}},
+		{"bow-tie", [][][2]float64{{{0, 0}, {1, 1}, {1, 0}, {0, 1}, {0, 0}}}, []error{ErrSelfIntersection}},
+		{"hole touching outer ring", [][][2]float64{square(0, 0, 1), {{0, 0.5}, {0.5, 0.8}, {0.5, 0.2}, {0, 0.5}}}, []error{ErrSelfIntersection}},
+		{"two points", [][][2]float64{{{0, 0}, {1, 1}, {0, 0}}}, []error{ErrRingTooShort}},
+		{"repeated points", [][][2]float64{{{0, 0}, {0, 0}, {1, 0}, {1, 0}, {0, 0}}}, []error{ErrRingTooShort}},
+		{"empty ring", [][][2]float64{{}}, []error{ErrRingTooShort}},
+		{"no rings", nil, []error{ErrEmptyShape}},
+		{"open and clockwise", [][][2]float64{{{0, 0}, {0, 1}, {1, 1}, {1, 0}}}, []error{ErrRingNotClosed, ErrWindingOrder}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Shape{Type: "Polygon", Coordinates: tt.coords}.Validate()
+			if err == nil {
+				t.Fatal("Validate() = nil")
+			}
+			for _, want := range tt.want {
+				if !errors.Is(err, want) {
+					t.Errorf("Validate() = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestShapeWithoutArea(t *testing.T) {
+	s := Shape{Coordinates: [][][2]float64{{{0, 0}, {1, 1}, {0, 0}}}}
+	if a := s.Area(); a != 0 {
+		t.Errorf("Area() = %f, want 0", a)
+	}
+	if _, _, ok := s.Centroid(); ok {
+		t.Error("Centroid() ok = true, want false")
+	}
+	if b := (Shape{}).BoundingBox(); b.Contains(0, 0) {
+		t.Errorf("empty BoundingBox() %+v contains 0, 0", b)
+	}
+}
@@ -0,0 +1,31 @@
+// Package geo provides the spherical earth computations shared by the packages of the module.
+package geo
+
+import "math"
+
+// EarthRadius is the mean earth radius in meters.
+const EarthRadius = 6371008.8
+
+// Distance returns the great-circle distance in meters between two points given in degrees,
+// computed with the haversine formula.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := radians(lat1), radians(lat2)
+	dPhi, dLambda := radians(lat2-lat1), radians(lon2-lon1)
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// RingArea returns the signed area in square meters of a ring of GeoJSON [longitude, latitude] pairs on
+// the sphere. It is positive for counterclockwise rings. The ring does not need to be closed.
+func RingArea(ring [][2]float64) float64 {
+	var sum float64
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		sum += radians(a[0]-b[0]) * (2 + math.Sin(radians(a[1])) + math.Sin(radians(b[1])))
+	}
+	return sum * EarthRadius * EarthRadius / 2
+}
@@ -0,0 +1,413 @@
+// Package kisfake provides an in-memory implementation of kis.Client for unit tests
+// of code depending on the Kubota API client.
+package kisfake
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+)
+
+// Client is an in-memory fake of the Kubota API client which answers all calls from its fields.
+//
+// Lookups by user name or mobile phone resolve the user in Users and return the data of all
+// machines with the same CompanyID. A non-empty subscription restricts the result to machines
+// with a matching SubscriptionID in Registries. Historical data is filtered by its Timestamp.
+// If no user or data is found, an *kis.APIError matching kis.ErrNotFound is returned.
+//
+// The fields must not be modified while the client is in use.
+type Client struct {
+	Users      []kis.User
+	Machines   []kis.Machine
+	Registries []kis.Registry
+	Positions  []kis.Position
+	Measures   []kis.Measure
+	Alarms     []kis.Alarm
+	Fields     []kis.Field
+	// Err, if set, is returned by every call.
+	Err error
+}
+
+var _ kis.Client = (*Client)(nil)
+
+// lookup fields, named like the query parameters of the API
+const (
+	byMobilePhone = "mobilePhone"
+	byUserName    = "userName"
+	byMachineUUID = "machineUUID"
+)
+
+// notFound returns the error the API responds with for unknown resources.
+func notFound(resource, field, value string) error {
+	return &kis.APIError{
+		Status:  http.StatusNotFound,
+		Type:    "NotFound",
+		Title:   "Not Found",
+		Details: []string{resource + " with " + field + " " + value + " not found"},
+	}
+}
+
+// check returns the configured error or the error of ctx.
+func (c *Client) check(ctx context.Context) error {
+	if c.Err != nil {
+		return c.Err
+	}
+	return ctx.Err()
+}
+
+// user finds the user by user name or mobile phone.
+func (c *Client) user(field, value string) (*kis.User, error) {
+	for i := range c.Users {
+		u := &c.Users[i]
+		if (field == byUserName && u.UserName == value) || (field == byMobilePhone && u.MobilePhone == value) {
+			return u, nil
+		}
+	}
+	return nil, notFound("user", field, value)
+}
+
+// machineUUIDs resolves a lookup to the set of matching machine UUIDs.
+func (c *Client) machineUUIDs(field, value, subscription string) (map[string]bool, error) {
+	uuids := make(map[string]bool)
+	if field == byMachineUUID {
+		uuids[value] = true
+	} else {
+		u, err := c.user(field, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range c.Machines {
+			if m.CompanyID == u.CompanyID {
+				uuids[m.MachineUUID] = true
+			}
+		}
+	}
+	if subscription != "" {
+		subscribed := make(map[string]bool)
+		for _, r := range c.Registries {
+			if r.SubscriptionID == subscription && uuids[r.MachineUUID] {
+				subscribed[r.MachineUUID] = true
+			}
+		}
+		uuids = subscribed
+	}
+	return uuids, nil
+}
+
+// historical returns the items of the matching machines within the date range.
+func historical[T any](ctx context.Context, c *Client, items []T, uuid func(T) string, ts func(T) time.Time, field, value, subscription string, startDate, endDate time.Time) ([]T, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	uuids, err := c.machineUUIDs(field, value, subscription)
+	if err != nil {
+		return nil, err
+	}
+	var res []T
+	for _, it := range items {
+		t := ts(it)
+		if !uuids[uuid(it)] || (!startDate.IsZero() && t.Before(startDate)) || (!endDate.IsZero() && t.After(endDate)) {
+			continue
+		}
+		res = append(res, it)
+	}
+	return res, nil
+}
+
+// first returns the first item of the matching machines.
+func first[T any](ctx context.Context, c *Client, resource string, items []T, uuid func(T) string, field, value, subscription string) (T, error) {
+	var zero T
+	if err := c.check(ctx); err != nil {
+		return zero, err
+	}
+	uuids, err := c.machineUUIDs(field, value, subscription)
+	if err != nil {
+		return zero, err
+	}
+	for _, it := range items {
+		if uuids[uuid(it)] {
+			return it, nil
+		}
+	}
+	return zero, notFound(resource, field, value)
+}
+
+func positionUUID(p kis.Position) string    { return p.MachineUUID }
+func positionTime(p kis.Position) time.Time { return p.Timestamp.Time }
+func measureUUID(m kis.Measure) string      { return m.MachineUUID }
+func measureTime(m kis.Measure) time.Time   { return m.Timestamp.Time }
+func alarmUUID(a kis.Alarm) string          { return a.MachineUUID }
+func alarmTime(a kis.Alarm) time.Time       { return a.Timestamp.Time }
+func machineUUIDOf(m kis.Machine) string    { return m.MachineUUID }
+func registryUUID(r kis.Registry) string    { return r.MachineUUID }
+
+// lastPosition returns the position with the latest timestamp of the matching machines.
+func (c *Client) lastPosition(ctx context.Context, field, value, subscription string) (*kis.Position, error) {
+	positions, err := historical(ctx, c, c.Positions, positionUUID, positionTime, field, value, subscription, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return nil, notFound("position", field, value)
+	}
+	last := positions[0]
+	for _, p := range positions[1:] {
+		if p.Timestamp.After(last.Timestamp.Time) {
+			last = p
+		}
+	}
+	return &last, nil
+}
+
+// GetLastPositionByMobilePhone implements kis.Positions.
+func (c *Client) GetLastPositionByMobilePhone(mobilePhone string, subscription string) (*kis.Position, error) {
+	return c.lastPosition(context.Background(), byMobilePhone, mobilePhone, subscription)
+}
+
+// GetLastPositionByMobilePhoneContext implements kis.Positions.
+func (c *Client) GetLastPositionByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (*kis.Position, error) {
+	return c.lastPosition(ctx, byMobilePhone, mobilePhone, subscription)
+}
+
+// GetLastPositionByUserName implements kis.Positions.
+func (c *Client) GetLastPositionByUserName(userName string, subscription string) (*kis.Position, error) {
+	return c.lastPosition(context.Background(), byUserName, userName, subscription)
+}
+
+// GetLastPositionByUserNameContext implements kis.Positions.
+func (c *Client) GetLastPositionByUserNameContext(ctx context.Context, userName string, subscription string) (*kis.Position, error) {
+	return c.lastPosition(ctx, byUserName, userName, subscription)
+}
+
+// GetLastPositionByMachineUUID implements kis.Positions.
+func (c *Client) GetLastPositionByMachineUUID(machineUUID string, subscription string) (*kis.Position, error) {
+	return c.lastPosition(context.Background(), byMachineUUID, machineUUID, subscription)
+}
+
+// GetLastPositionByMachineUUIDContext implements kis.Positions.
+func (c *Client) GetLastPositionByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (*kis.Position, error) {
+	return c.lastPosition(ctx, byMachineUUID, machineUUID, subscription)
+}
+
+// GetHistoricalPositionByMobilePhone implements kis.Positions.
+func (c *Client) GetHistoricalPositionByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]kis.Position, error) {
+	return historical(context.Background(), c, c.Positions, positionUUID, positionTime, byMobilePhone, mobilePhone, subscription, startDate, endDate)
+}
+
+// GetHistoricalPositionByMobilePhoneContext implements kis.Positions.
+func (c *Client) GetHistoricalPositionByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]kis.Position, error) {
+	return historical(ctx, c, c.Positions, positionUUID, positionTime, byMobilePhone, mobilePhone, subscription, startDate, endDate)
+}
+
+// GetHistoricalPositionByUserName implements kis.Positions.
+func (c *Client) GetHistoricalPositionByUserName(userName, subscription string, startDate, endDate time.Time) ([]kis.Position, error) {
+	return historical(context.Background(), c, c.Positions, positionUUID, positionTime, byUserName, userName, subscription, startDate, endDate)
+}
+
+// GetHistoricalPositionByUserNameContext implements kis.Positions.
+func (c *Client) GetHistoricalPositionByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]kis.Position, error) {
+	return historical(ctx, c, c.Positions, positionUUID, positionTime, byUserName, userName, subscription, startDate, endDate)
+}
+
+// GetHistoricalPositionByMachineUUID implements kis.Positions.
+func (c *Client) GetHistoricalPositionByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]kis.Position, error) {
+	return historical(context.Background(), c, c.Positions, positionUUID, positionTime, byMachineUUID, machineUUID, subscription, startDate, endDate)
+}
+
+// GetHistoricalPositionByMachineUUIDContext implements kis.Positions.
+func (c *Client) GetHistoricalPositionByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]kis.Position, error) {
+	return historical(ctx, c, c.Positions, positionUUID, positionTime, byMachineUUID, machineUUID, subscription, startDate, endDate)
+}
+
+// GetHistoricalMeasureByMobilePhone implements kis.Measures.
+func (c *Client) GetHistoricalMeasureByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]kis.Measure, error) {
+	return historical(context.Background(), c, c.Measures, measureUUID, measureTime, byMobilePhone, mobilePhone, subscription, startDate, endDate)
+}
+
+// GetHistoricalMeasureByMobilePhoneContext implements kis.Measures.
+func (c *Client) GetHistoricalMeasureByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]kis.Measure, error) {
+	return historical(ctx, c, c.Measures, measureUUID, measureTime, byMobilePhone, mobilePhone, subscription, startDate, endDate)
+}
+
+// GetHistoricalMeasureByUserName implements kis.Measures.
+func (c *Client) GetHistoricalMeasureByUserName(userName, subscription string, startDate, endDate time.Time) ([]kis.Measure, error) {
+	return historical(context.Background(), c, c.Measures, measureUUID, measureTime, byUserName, userName, subscription, startDate, endDate)
+}
+
+// GetHistoricalMeasureByUserNameContext implements kis.Measures.
+func (c *Client) GetHistoricalMeasureByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]kis.Measure, error) {
+	return historical(ctx, c, c.Measures, measureUUID, measureTime, byUserName, userName, subscription, startDate, endDate)
+}
+
+// GetHistoricalMeasureByMachineUUID implements kis.Measures.
+func (c *Client) GetHistoricalMeasureByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]kis.Measure, error) {
+	return historical(context.Background(), c, c.Measures, measureUUID, measureTime, byMachineUUID, machineUUID, subscription, startDate, endDate)
+}
+
+// GetHistoricalMeasureByMachineUUIDContext implements kis.Measures.
+func (c *Client) GetHistoricalMeasureByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]kis.Measure, error) {
+	return historical(ctx, c, c.Measures, measureUUID, measureTime, byMachineUUID, machineUUID, subscription, startDate, endDate)
+}
+
+// GetHistoricalAlarmByMobilePhone implements kis.Alarms.
+func (c *Client) GetHistoricalAlarmByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]kis.Alarm, error) {
+	return historical(context.Background(), c, c.Alarms, alarmUUID, alarmTime, byMobilePhone, mobilePhone, subscription, startDate, endDate)
+}
+
+// GetHistoricalAlarmByMobilePhoneContext implements kis.Alarms.
+func (c *Client) GetHistoricalAlarmByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]kis.Alarm, error) {
+	return historical(ctx, c, c.Alarms, alarmUUID, alarmTime, byMobilePhone, mobilePhone, subscription, startDate, endDate)
+}
+
+// GetHistoricalAlarmByUserName implements kis.Alarms.
+func (c *Client) GetHistoricalAlarmByUserName(userName, subscription string, startDate, endDate time.Time) ([]kis.Alarm, error) {
+	return historical(context.Background(), c, c.Alarms, alarmUUID, alarmTime, byUserName, userName, subscription, startDate, endDate)
+}
+
+// GetHistoricalAlarmByUserNameContext implements kis.Alarms.
+func (c *Client) GetHistoricalAlarmByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]kis.Alarm, error) {
+	return historical(ctx, c, c.Alarms, alarmUUID, alarmTime, byUserName, userName, subscription, startDate, endDate)
+}
+
+// GetHistoricalAlarmByMachineUUID implements kis.Alarms.
+func (c *Client) GetHistoricalAlarmByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]kis.Alarm, error) {
+	return historical(context.Background(), c, c.Alarms, alarmUUID, alarmTime, byMachineUUID, machineUUID, subscription, startDate, endDate)
+}
+
+// GetHistoricalAlarmByMachineUUIDContext implements kis.Alarms.
+func (c *Client) GetHistoricalAlarmByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]kis.Alarm, error) {
+	return historical(ctx, c, c.Alarms, alarmUUID, alarmTime, byMachineUUID, machineUUID, subscription, startDate, endDate)
+}
+
+// GetMachineByMobilePhone implements kis.Machines.
+func (c *Client) GetMachineByMobilePhone(mobilePhone string, subscription string) (kis.Machine, error) {
+	return first(context.Background(), c, "machine", c.Machines, machineUUIDOf, byMobilePhone, mobilePhone, subscription)
+}
+
+// GetMachineByMobilePhoneContext implements kis.Machines.
+func (c *Client) GetMachineByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (kis.Machine, error) {
+	return first(ctx, c, "machine", c.Machines, machineUUIDOf, byMobilePhone, mobilePhone, subscription)
+}
+
+// GetMachineByUserName implements kis.Machines.
+func (c *Client) GetMachineByUserName(userName string, subscription string) (kis.Machine, error) {
+	return first(context.Background(), c, "machine", c.Machines, machineUUIDOf, byUserName, userName, subscription)
+}
+
+// GetMachineByUserNameContext implements kis.Machines.
+func (c *Client) GetMachineByUserNameContext(ctx context.Context, userName string, subscription string) (kis.Machine, error) {
+	return first(ctx, c, "machine", c.Machines, machineUUIDOf, byUserName, userName, subscription)
+}
+
+// GetMachineByMachineUUID implements kis.Machines.
+func (c *Client) GetMachineByMachineUUID(machineUUID string, subscription string) (kis.Machine, error) {
+	return first(context.Background(), c, "machine", c.Machines, machineUUIDOf, byMachineUUID, machineUUID, subscription)
+}
+
+// GetMachineByMachineUUIDContext implements kis.Machines.
+func (c *Client) GetMachineByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (kis.Machine, error) {
+	return first(ctx, c, "machine", c.Machines, machineUUIDOf, byMachineUUID, machineUUID, subscription)
+}
+
+// GetRegistryByMobilePhone implements kis.Registries.
+func (c *Client) GetRegistryByMobilePhone(mobilePhone string, subscription string) (kis.Registry, error) {
+	return first(context.Background(), c, "registry", c.Registries, registryUUID, byMobilePhone, mobilePhone, subscription)
+}
+
+// GetRegistryByMobilePhoneContext implements kis.Registries.
+func (c *Client) GetRegistryByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (kis.Registry, error) {
+	return first(ctx, c, "registry", c.Registries, registryUUID, byMobilePhone, mobilePhone, subscription)
+}
+
+// GetRegistryByUserName implements kis.Registries.
+func (c *Client) GetRegistryByUserName(userName string, subscription string) (kis.Registry, error) {
+	return first(context.Background(), c, "registry", c.Registries, registryUUID, byUserName, userName, subscription)
+}
+
+// GetRegistryByUserNameContext implements kis.Registries.
+func (c *Client) GetRegistryByUserNameContext(ctx context.Context, userName string, subscription string) (kis.Registry, error) {
+	return first(ctx, c, "registry", c.Registries, registryUUID, byUserName, userName, subscription)
+}
+
+// GetRegistryByMachineUUID implements kis.Registries.
+func (c *Client) GetRegistryByMachineUUID(machineUUID string, subscription string) (kis.Registry, error) {
+	return first(context.Background(), c, "registry", c.Registries, registryUUID, byMachineUUID, machineUUID, subscription)
+}
+
+// GetRegistryByMachineUUIDContext implements kis.Registries.
+func (c *Client) GetRegistryByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (kis.Registry, error) {
+	return first(ctx, c, "registry", c.Registries, registryUUID, byMachineUUID, machineUUID, subscription)
+}
+
+// getUser returns a copy of the user found by user name or mobile phone.
+func (c *Client) getUser(ctx context.Context, field, value string) (*kis.User, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	u, err := c.user(field, value)
+	if err != nil {
+		return nil, err
+	}
+	res := *u
+	return &res, nil
+}
+
+// GetUserByMobilePhone implements kis.Users.
+func (c *Client) GetUserByMobilePhone(mobilePhone string) (*kis.User, error) {
+	return c.getUser(context.Background(), byMobilePhone, mobilePhone)
+}
+
+// GetUserByMobilePhoneContext implements kis.Users.
+func (c *Client) GetUserByMobilePhoneContext(ctx context.Context, mobilePhone string) (*kis.User, error) {
+	return c.getUser(ctx, byMobilePhone, mobilePhone)
+}
+
+// GetUserByUserName implements kis.Users.
+func (c *Client) GetUserByUserName(userName string) (*kis.User, error) {
+	return c.getUser(context.Background(), byUserName, userName)
+}
+
+// GetUserByUserNameContext implements kis.Users.
+func (c *Client) GetUserByUserNameContext(ctx context.Context, userName string) (*kis.User, error) {
+	return c.getUser(ctx, byUserName, userName)
+}
+
+// getField returns the fields of the company of the user found by user name or mobile phone.
+func (c *Client) getField(ctx context.Context, field, value string) ([]kis.Field, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	u, err := c.user(field, value)
+	if err != nil {
+		return nil, err
+	}
+	var res []kis.Field
+	for _, f := range c.Fields {
+		if f.CompanyID == u.CompanyID {
+			res = append(res, f)
+		}
+	}
+	return res, nil
+}
+
+// GetFieldByMobilePhone implements kis.Fields.
+func (c *Client) GetFieldByMobilePhone(mobilePhone string) ([]kis.Field, error) {
+	return c.getField(context.Background(), byMobilePhone, mobilePhone)
+}
+
+// GetFieldByMobilePhoneContext implements kis.Fields.
+func (c *Client) GetFieldByMobilePhoneContext(ctx context.Context, mobilePhone string) ([]kis.Field, error) {
+	return c.getField(ctx, byMobilePhone, mobilePhone)
+}
+
+// GetFieldByUserName implements kis.Fields.
+func (c *Client) GetFieldByUserName(userName string) ([]kis.Field, error) {
+	return c.getField(context.Background(), byUserName, userName)
+}
+
+// GetFieldByUserNameContext implements kis.Fields.
+func (c *Client) GetFieldByUserNameContext(ctx context.Context, userName string) ([]kis.Field, error) {
+	return c.getField(ctx, byUserName, userName)
+}
@@ -0,0 +1,15 @@
+module github.com/maltegrosse/go-kubota-kis-api/kisotel
+
+go 1.22.1
+
+require (
+	github.com/maltegrosse/go-kubota-kis-api v0.1.0
+	go.opentelemetry.io/otel v1.32.0
+	go.opentelemetry.io/otel/trace v1.32.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.32.0 // indirect
+)
@@ -0,0 +1,106 @@
+// Package kisotel provides OpenTelemetry tracing for the Kubota API client.
+//
+//	k, err := kis.NewKIS(publicKey, secretKey, endpoint, kis.WithTracer(kisotel.NewTracer()))
+package kisotel
+
+import (
+	"context"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer of this package.
+const instrumentationName = "github.com/maltegrosse/go-kubota-kis-api/kisotel"
+
+// Attribute keys set on the spans.
+const (
+	EndpointKey     = attribute.Key("kis.endpoint")
+	LookupFieldKey  = attribute.Key("kis.lookup_field")
+	SubscriptionKey = attribute.Key("kis.subscription")
+	StartDateKey    = attribute.Key("kis.start_date")
+	EndDateKey      = attribute.Key("kis.end_date")
+	PayloadItemsKey = attribute.Key("kis.payload.items")
+	LogIDKey        = attribute.Key("kis.log_id")
+	StatusCodeKey   = attribute.Key("http.response.status_code")
+)
+
+// Option configures a Tracer.
+type Option func(*Tracer)
+
+// WithTracerProvider sets the tracer provider, by default the global provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.provider = tp
+	}
+}
+
+// Tracer implements kis.Tracer and creates a client span named after the getter,
+// e.g. "kis.GetHistoricalPosition", for every call.
+type Tracer struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+}
+
+var _ kis.Tracer = (*Tracer)(nil)
+
+// NewTracer creates a new Tracer.
+func NewTracer(opts ...Option) *Tracer {
+	t := &Tracer{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.provider == nil {
+		t.provider = otel.GetTracerProvider()
+	}
+	t.tracer = t.provider.Tracer(instrumentationName)
+	return t
+}
+
+// Start implements kis.Tracer.
+func (t *Tracer) Start(ctx context.Context, call kis.CallInfo) (context.Context, kis.Span) {
+	attrs := []attribute.KeyValue{
+		EndpointKey.String(call.Endpoint),
+		LookupFieldKey.String(call.Field),
+	}
+	if call.Subscription != "" {
+		attrs = append(attrs, SubscriptionKey.String(call.Subscription))
+	}
+	if !call.StartDate.IsZero() {
+		attrs = append(attrs, StartDateKey.String(call.StartDate.UTC().Format(time.RFC3339)))
+	}
+	if !call.EndDate.IsZero() {
+		attrs = append(attrs, EndDateKey.String(call.EndDate.UTC().Format(time.RFC3339)))
+	}
+	ctx, span := t.tracer.Start(ctx, "kis."+call.Operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, &callSpan{span: span}
+}
+
+// callSpan adapts an OpenTelemetry span to kis.Span.
+type callSpan struct {
+	span trace.Span
+}
+
+// End implements kis.Span.
+func (s *callSpan) End(result kis.CallResult) {
+	if result.Status != 0 {
+		s.span.SetAttributes(StatusCodeKey.Int(result.Status))
+	}
+	if result.LogID != "" {
+		s.span.SetAttributes(LogIDKey.String(result.LogID))
+	}
+	if result.Err != nil {
+		s.span.RecordError(result.Err)
+		s.span.SetStatus(codes.Error, result.Err.Error())
+	} else {
+		s.span.SetAttributes(PayloadItemsKey.Int(result.Items))
+	}
+	s.span.End()
+}
@@ -0,0 +1,181 @@
+// Package kisprom provides Prometheus metrics for the Kubota API client.
+//
+//	c := kisprom.NewCollector()
+//	prometheus.MustRegister(c)
+//	k, err := kis.NewKIS(publicKey, secretKey, endpoint, kis.WithMetrics(c))
+package kisprom
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option configures a Collector.
+type Option func(*options)
+
+type options struct {
+	namespace   string
+	constLabels prometheus.Labels
+	buckets     []float64
+}
+
+// WithNamespace sets the namespace of the metric names, which defaults to "kis".
+func WithNamespace(ns string) Option {
+	return func(o *options) {
+		o.namespace = ns
+	}
+}
+
+// WithConstLabels adds constant labels to all metrics, e.g. to distinguish several clients.
+func WithConstLabels(l prometheus.Labels) Option {
+	return func(o *options) {
+		o.constLabels = l
+	}
+}
+
+// WithBuckets sets the buckets of the request latency histogram in seconds.
+func WithBuckets(b []float64) Option {
+	return func(o *options) {
+		o.buckets = b
+	}
+}
+
+// Collector implements kis.MetricsCollector and prometheus.Collector.
+type Collector struct {
+	requests       *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	rateLimited    *prometheus.CounterVec
+	retries        *prometheus.CounterVec
+	rateLimitWaits *prometheus.HistogramVec
+	tokenRefreshes *prometheus.CounterVec
+	payloadItems   *prometheus.HistogramVec
+	payloadBytes   *prometheus.HistogramVec
+}
+
+var (
+	_ kis.MetricsCollector = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// NewCollector creates a new Collector, which has to be registered with a prometheus.Registerer.
+func NewCollector(opts ...Option) *Collector {
+	o := &options{
+		namespace: "kis",
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "requests_total",
+			Help:        "Number of HTTP requests to the KIS API by endpoint and status code, 0 for requests without response.",
+			ConstLabels: o.constLabels,
+		}, []string{"endpoint", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "request_duration_seconds",
+			Help:        "Latency of HTTP requests to the KIS API by endpoint and status code.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"endpoint", "status"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "rate_limited_total",
+			Help:        "Number of responses with status 429 by endpoint.",
+			ConstLabels: o.constLabels,
+		}, []string{"endpoint"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "retries_total",
+			Help:        "Number of retried requests by endpoint.",
+			ConstLabels: o.constLabels,
+		}, []string{"endpoint"}),
+		rateLimitWaits: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "rate_limit_wait_seconds",
+			Help:        "Time requests were delayed by the client-side rate limiter by endpoint.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"endpoint"}),
+		tokenRefreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "token_refreshes_total",
+			Help:        "Number of access token refreshes by result.",
+			ConstLabels: o.constLabels,
+		}, []string{"result"}),
+		payloadItems: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "payload_items",
+			Help:        "Number of items in decoded response payloads by endpoint.",
+			ConstLabels: o.constLabels,
+			Buckets:     prometheus.ExponentialBuckets(1, 4, 10),
+		}, []string{"endpoint"}),
+		payloadBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "payload_bytes",
+			Help:        "Size of decoded response bodies in bytes by endpoint.",
+			ConstLabels: o.constLabels,
+			Buckets:     prometheus.ExponentialBuckets(256, 4, 10),
+		}, []string{"endpoint"}),
+	}
+}
+
+// metrics returns all metrics of the collector.
+func (c *Collector) metrics() []prometheus.Collector {
+	return []prometheus.Collector{c.requests, c.latency, c.rateLimited, c.retries, c.rateLimitWaits, c.tokenRefreshes, c.payloadItems, c.payloadBytes}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics() {
+		m.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.metrics() {
+		m.Collect(ch)
+	}
+}
+
+// ObserveRequest implements kis.MetricsCollector.
+func (c *Collector) ObserveRequest(endpoint string, status int, latency time.Duration) {
+	s := strconv.Itoa(status)
+	c.requests.WithLabelValues(endpoint, s).Inc()
+	c.latency.WithLabelValues(endpoint, s).Observe(latency.Seconds())
+	if status == http.StatusTooManyRequests {
+		c.rateLimited.WithLabelValues(endpoint).Inc()
+	}
+}
+
+// ObserveRetry implements kis.MetricsCollector.
+func (c *Collector) ObserveRetry(endpoint string) {
+	c.retries.WithLabelValues(endpoint).Inc()
+}
+
+// ObserveRateLimitWait implements kis.MetricsCollector.
+func (c *Collector) ObserveRateLimitWait(endpoint string, wait time.Duration) {
+	c.rateLimitWaits.WithLabelValues(endpoint).Observe(wait.Seconds())
+}
+
+// ObserveTokenRefresh implements kis.MetricsCollector.
+func (c *Collector) ObserveTokenRefresh(err error) {
+	if err != nil {
+		c.tokenRefreshes.WithLabelValues("failure").Inc()
+		return
+	}
+	c.tokenRefreshes.WithLabelValues("success").Inc()
+}
+
+// ObservePayload implements kis.MetricsCollector.
+func (c *Collector) ObservePayload(endpoint string, items int, bytes int64) {
+	c.payloadItems.WithLabelValues(endpoint).Observe(float64(items))
+	c.payloadBytes.WithLabelValues(endpoint).Observe(float64(bytes))
+}
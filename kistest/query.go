@@ -0,0 +1,145 @@
+package kistest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"github.com/maltegrosse/go-kubota-kis-api/kisfake"
+)
+
+// dateLayouts are the accepted formats of the startDate and endDate parameters.
+var dateLayouts = []string{"2006-01-02T15:04:05", time.RFC3339Nano}
+
+// query holds the parsed parameters of a resource request.
+type query struct {
+	field        string
+	value        string
+	subscription string
+	startDate    time.Time
+	endDate      time.Time
+}
+
+// historical reports whether a date range was requested.
+func (q *query) historical() bool {
+	return !q.startDate.IsZero() || !q.endDate.IsZero()
+}
+
+func parseQuery(r *http.Request) (*query, error) {
+	v := r.URL.Query()
+	q := &query{subscription: v.Get("subscription")}
+	for _, field := range []string{"machineUUID", "userName", "mobilePhone"} {
+		if v.Has(field) {
+			q.field, q.value = field, v.Get(field)
+			break
+		}
+	}
+	if q.field == "" {
+		return nil, errors.New("one of machineUUID, userName or mobilePhone is required")
+	}
+	var err error
+	if q.startDate, err = parseDate(v.Get("startDate")); err != nil {
+		return nil, err
+	}
+	if q.endDate, err = parseDate(v.Get("endDate")); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// lookup answers the request for resource from the fixtures.
+// Position requests without a date range return the last position.
+func (q *query) lookup(r *http.Request, c *kisfake.Client, resource string) (any, error) {
+	ctx := r.Context()
+	switch resource {
+	case "position":
+		if q.historical() {
+			switch q.field {
+			case "machineUUID":
+				return c.GetHistoricalPositionByMachineUUIDContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+			case "userName":
+				return c.GetHistoricalPositionByUserNameContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+			default:
+				return c.GetHistoricalPositionByMobilePhoneContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+			}
+		}
+		switch q.field {
+		case "machineUUID":
+			return c.GetLastPositionByMachineUUIDContext(ctx, q.value, q.subscription)
+		case "userName":
+			return c.GetLastPositionByUserNameContext(ctx, q.value, q.subscription)
+		default:
+			return c.GetLastPositionByMobilePhoneContext(ctx, q.value, q.subscription)
+		}
+	case "measure":
+		switch q.field {
+		case "machineUUID":
+			return c.GetHistoricalMeasureByMachineUUIDContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+		case "userName":
+			return c.GetHistoricalMeasureByUserNameContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+		default:
+			return c.GetHistoricalMeasureByMobilePhoneContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+		}
+	case "alarm":
+		switch q.field {
+		case "machineUUID":
+			return c.GetHistoricalAlarmByMachineUUIDContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+		case "userName":
+			return c.GetHistoricalAlarmByUserNameContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+		default:
+			return c.GetHistoricalAlarmByMobilePhoneContext(ctx, q.value, q.subscription, q.startDate, q.endDate)
+		}
+	case "machine":
+		switch q.field {
+		case "machineUUID":
+			return c.GetMachineByMachineUUIDContext(ctx, q.value, q.subscription)
+		case "userName":
+			return c.GetMachineByUserNameContext(ctx, q.value, q.subscription)
+		default:
+			return c.GetMachineByMobilePhoneContext(ctx, q.value, q.subscription)
+		}
+	case "registry":
+		switch q.field {
+		case "machineUUID":
+			return c.GetRegistryByMachineUUIDContext(ctx, q.value, q.subscription)
+		case "userName":
+			return c.GetRegistryByUserNameContext(ctx, q.value, q.subscription)
+		default:
+			return c.GetRegistryByMobilePhoneContext(ctx, q.value, q.subscription)
+		}
+	case "user":
+		switch q.field {
+		case "userName":
+			return c.GetUserByUserNameContext(ctx, q.value)
+		case "mobilePhone":
+			return c.GetUserByMobilePhoneContext(ctx, q.value)
+		}
+	case "field":
+		switch q.field {
+		case "userName":
+			return c.GetFieldByUserNameContext(ctx, q.value)
+		case "mobilePhone":
+			return c.GetFieldByMobilePhoneContext(ctx, q.value)
+		}
+	}
+	return nil, &kis.APIError{
+		Status:  http.StatusBadRequest,
+		Type:    "BadRequest",
+		Title:   "Bad Request",
+		Details: []string{fmt.Sprintf("%s cannot be looked up by %s", resource, q.field)},
+	}
+}
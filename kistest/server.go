@@ -0,0 +1,272 @@
+// Package kistest provides an in-process fake of the KIS API for end to end tests of
+// code using the Kubota API client without network access.
+package kistest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"github.com/maltegrosse/go-kubota-kis-api/kisfake"
+)
+
+// APIPath is the path prefix under which the server serves the API.
+const APIPath = "/api/v1"
+
+// Failure describes an error response returned instead of the regular response.
+type Failure struct {
+	// Status is the HTTP status code of the response, e.g. 401, 429 or 500.
+	Status int
+	// RetryAfter is sent as Retry-After header if not empty, in seconds or as HTTP-date.
+	RetryAfter string
+	// Times is the number of requests which fail, 0 means one request.
+	Times int
+	// Body replaces the error body if not empty, e.g. to inject a malformed response with status 200.
+	Body string
+}
+
+// Server is a fake KIS API server. It serves the token endpoint and the position, measure,
+// alarm, machine, registry, user and field endpoints with the data of Data, using the same
+// envelope and error body as the real API.
+type Server struct {
+	*httptest.Server
+	// Data holds the fixtures served by the resource endpoints.
+	Data *kisfake.Client
+	// PublicKey and SecretKey are the keys accepted by the token endpoint. If empty, any key is accepted.
+	PublicKey string
+	SecretKey string
+	// TokenTTL is the lifetime of issued tokens, sent as ExpiresIn in minutes.
+	TokenTTL time.Duration
+
+	mu       sync.Mutex
+	tokens   map[string]time.Time
+	failures map[string][]Failure
+	requests map[string]int
+}
+
+// NewServer starts a server which serves the given fixtures. The caller should call Close when finished.
+func NewServer(data *kisfake.Client) *Server {
+	if data == nil {
+		data = &kisfake.Client{}
+	}
+	s := &Server{
+		Data:     data,
+		TokenTTL: time.Hour,
+		tokens:   make(map[string]time.Time),
+		failures: make(map[string][]Failure),
+		requests: make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(APIPath+"/authorization/token", s.handleToken)
+	for _, resource := range []string{"position", "measure", "alarm", "machine", "registry", "user", "field"} {
+		mux.HandleFunc(APIPath+"/"+resource, s.handleResource(resource))
+	}
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Start starts a server like NewServer and closes it when the test finishes.
+func Start(tb testing.TB, data *kisfake.Client) *Server {
+	tb.Helper()
+	s := NewServer(data)
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// NewClient returns a client of the server, authenticated with PublicKey and SecretKey or with
+// arbitrary keys if they are empty. The test fails if the client cannot be created, and the client
+// is closed when the test finishes.
+func (s *Server) NewClient(tb testing.TB, opts ...kis.Option) *kis.Kubota {
+	tb.Helper()
+	publicKey, secretKey := s.PublicKey, s.SecretKey
+	if publicKey == "" {
+		publicKey = "public"
+	}
+	if secretKey == "" {
+		secretKey = "secret"
+	}
+	k, err := kis.NewKIS(publicKey, secretKey, s.URL, opts...)
+	if err != nil {
+		tb.Fatalf("error creating client: %v", err)
+	}
+	tb.Cleanup(func() { k.Close() })
+	return k
+}
+
+// Fail makes the next requests to endpoint fail as described by f. The endpoint is "token"
+// or the name of a resource like "position"; an empty endpoint matches all requests.
+// Failures of an endpoint are applied in the order they were added.
+func (s *Server) Fail(endpoint string, f Failure) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.mu.Lock()
+	s.failures[endpoint] = append(s.failures[endpoint], f)
+	s.mu.Unlock()
+}
+
+// ExpireTokens invalidates all issued tokens, so the next requests are answered with 401.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	clear(s.tokens)
+	s.mu.Unlock()
+}
+
+// Requests returns the number of requests received by endpoint, including failed ones.
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+// begin counts the request and returns the failure to apply, if any.
+func (s *Server) begin(endpoint string) *Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[endpoint]++
+	for _, key := range []string{endpoint, ""} {
+		queue := s.failures[key]
+		if len(queue) == 0 {
+			continue
+		}
+		f := queue[0]
+		if queue[0].Times--; queue[0].Times == 0 {
+			s.failures[key] = queue[1:]
+		}
+		return &f
+	}
+	return nil
+}
+
+// authorized reports whether the request carries a valid token.
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.tokens[token]
+	return ok && time.Now().Before(expiry)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if f := s.begin("token"); f != nil {
+		writeFailure(w, f)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if (s.PublicKey != "" && r.PostForm.Get("publicKey") != s.PublicKey) || (s.SecretKey != "" && r.PostForm.Get("secretKey") != s.SecretKey) {
+		writeError(w, http.StatusUnauthorized, "invalid public or secret key")
+		return
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(s.TokenTTL)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"AccessToken": token,
+		"TokenType":   "Bearer",
+		"ExpiresIn":   int(s.TokenTTL / time.Minute),
+	})
+}
+
+// handleResource serves a resource endpoint from the fixtures.
+func (s *Server) handleResource(resource string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if f := s.begin(resource); f != nil {
+			writeFailure(w, f)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+			return
+		}
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "invalid or expired access token")
+			return
+		}
+		q, err := parseQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		payload, err := q.lookup(r, s.Data, resource)
+		if err != nil {
+			var apiErr *kis.APIError
+			if errors.As(err, &apiErr) {
+				writeJSON(w, apiErr.Status, errorBody{Type: apiErr.Type, Title: apiErr.Title, Status: apiErr.Status, LogID: apiErr.LogID, Details: apiErr.Details})
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Status: http.StatusOK, Resource: resource, Payload: payload})
+	}
+}
+
+// envelope is the body of every successful resource response.
+type envelope struct {
+	Status   int    `json:"Status"`
+	Resource string `json:"Resource"`
+	Payload  any    `json:"Payload"`
+}
+
+// errorBody is the body of every error response.
+type errorBody struct {
+	Type    string   `json:"Type"`
+	Title   string   `json:"Title"`
+	Status  int      `json:"Status"`
+	LogID   string   `json:"LogId"`
+	Details []string `json:"Details"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	writeJSON(w, status, errorBody{
+		Type:    strings.ReplaceAll(http.StatusText(status), " ", ""),
+		Title:   http.StatusText(status),
+		Status:  status,
+		LogID:   hex.EncodeToString(b),
+		Details: []string{detail},
+	})
+}
+
+func writeFailure(w http.ResponseWriter, f *Failure) {
+	if f.RetryAfter != "" {
+		w.Header().Set("Retry-After", f.RetryAfter)
+	}
+	if f.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(f.Status)
+		_, _ = io.WriteString(w, f.Body)
+		return
+	}
+	writeError(w, f.Status, fmt.Sprintf("injected failure with status %d", f.Status))
+}
@@ -0,0 +1,184 @@
+package kistest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"github.com/maltegrosse/go-kubota-kis-api/kisfake"
+	"github.com/maltegrosse/go-kubota-kis-api/kistest"
+)
+
+var start = time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)
+
+func position(machineUUID string, minute int, lat, lon float64) kis.Position {
+	return kis.Position{
+		MachineUUID: machineUUID,
+		Latitude:    lat,
+		Longitude:   lon,
+		Timestamp:   kis.CustomTime{Time: start.Add(time.Duration(minute) * time.Minute)},
+	}
+}
+
+// newClient starts a server with positions of machines m1 and m2 and a client authenticated against it.
+func newClient(t *testing.T) (*kistest.Server, *kis.Kubota) {
+	t.Helper()
+	srv := kistest.Start(t, &kisfake.Client{Positions: []kis.Position{
+		position("m1", 0, 52.1, 8.1),
+		position("m1", 10, 52.2, 8.2),
+		position("m1", 20, 52.3, 8.3),
+		position("m2", 5, 48.0, 11.0),
+	}})
+	srv.PublicKey, srv.SecretKey = "public", "secret"
+	k := srv.NewClient(t, kis.WithRetryPolicy(kis.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		MaxRetryAfter:  5 * time.Second,
+	}))
+	return srv, k
+}
+
+func TestInvalidKeys(t *testing.T) {
+	srv := kistest.Start(t, nil)
+	srv.PublicKey, srv.SecretKey = "public", "secret"
+	_, err := kis.NewKIS("public", "wrong", srv.URL)
+	if !errors.Is(err, kis.ErrUnauthorized) {
+		t.Fatalf("NewKIS() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestLastPosition(t *testing.T) {
+	_, k := newClient(t)
+	pos, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Latitude != 52.3 || !pos.Timestamp.Equal(start.Add(20*time.Minute)) {
+		t.Errorf("last position = %+v, want the one at 08:20", pos)
+	}
+}
+
+func TestHistoricalPositions(t *testing.T) {
+	_, k := newClient(t)
+	positions, err := k.GetHistoricalPositionByMachineUUIDContext(context.Background(), "m1", "", start.Add(5*time.Minute), start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("got %d positions, want 2", len(positions))
+	}
+	for i, want := range []time.Time{start.Add(10 * time.Minute), start.Add(20 * time.Minute)} {
+		if !positions[i].Timestamp.Equal(want) {
+			t.Errorf("positions[%d].Timestamp = %v, want %v", i, positions[i].Timestamp, want)
+		}
+	}
+}
+
+func TestReauthenticateAfterExpiry(t *testing.T) {
+	srv, k := newClient(t)
+	ctx := context.Background()
+	if _, err := k.GetLastPositionByMachineUUIDContext(ctx, "m1", ""); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	if _, err := k.GetLastPositionByMachineUUIDContext(ctx, "m1", ""); err != nil {
+		t.Fatalf("request after token expiry: %v", err)
+	}
+	if n := srv.Requests("token"); n != 2 {
+		t.Errorf("token requests = %d, want 2", n)
+	}
+	// the request with the expired token is sent once more with the new one
+	if n := srv.Requests("position"); n != 3 {
+		t.Errorf("position requests = %d, want 3", n)
+	}
+}
+
+func TestRetryTooManyRequests(t *testing.T) {
+	srv, k := newClient(t)
+	srv.Fail("position", kistest.Failure{Status: 429, RetryAfter: "1"})
+	begin := time.Now()
+	pos, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m2", "")
+	if err != nil {
+		t.Fatalf("request after injected 429: %v", err)
+	}
+	if pos.MachineUUID != "m2" {
+		t.Errorf("MachineUUID = %q, want m2", pos.MachineUUID)
+	}
+	if n := srv.Requests("position"); n != 2 {
+		t.Errorf("position requests = %d, want 2", n)
+	}
+	if d := time.Since(begin); d < time.Second {
+		t.Errorf("retried after %v, want Retry-After of 1s to be honored", d)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	srv, k := newClient(t)
+	srv.Fail("position", kistest.Failure{Status: 503, Times: 3})
+	_, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m1", "")
+	if !errors.Is(err, kis.ErrServer) {
+		t.Fatalf("error = %v, want ErrServer", err)
+	}
+	if n := srv.Requests("position"); n != 3 {
+		t.Errorf("position requests = %d, want 3", n)
+	}
+}
+
+func TestServerError(t *testing.T) {
+	srv, k := newClient(t)
+	srv.Fail("position", kistest.Failure{Status: 500})
+	_, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m1", "")
+	if !errors.Is(err, kis.ErrServer) {
+		t.Fatalf("error = %v, want ErrServer", err)
+	}
+	var apiErr *kis.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %T, want *kis.APIError", err)
+	}
+	if apiErr.Status != 500 || apiErr.LogID == "" || len(apiErr.Details) != 1 {
+		t.Errorf("APIError = %+v, want status 500 with LogID and details", apiErr)
+	}
+	// 500 is not retried
+	if n := srv.Requests("position"); n != 1 {
+		t.Errorf("position requests = %d, want 1", n)
+	}
+	if _, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "m1", ""); err != nil {
+		t.Errorf("request after the failure: %v", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	_, k := newClient(t)
+	_, err := k.GetLastPositionByMachineUUIDContext(context.Background(), "unknown", "")
+	if !errors.Is(err, kis.ErrNotFound) {
+		t.Fatalf("error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestResponseMeta(t *testing.T) {
+	_, k := newClient(t)
+	ctx := context.Background()
+	pos, meta, err := k.GetLastPositionByMachineUUIDWithMeta(ctx, "m2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.MachineUUID != "m2" || meta != (kis.ResponseMeta{Status: 200, Resource: "position"}) {
+		t.Errorf("GetLastPositionByMachineUUIDWithMeta() = %+v, %+v, want m2 with status 200 of resource position", pos, meta)
+	}
+
+	s := k.ScanHistoricalPositionByMachineUUID(ctx, "m1", "", start, start.Add(time.Hour), kis.ScanOptions{})
+	defer s.Close()
+	if !s.Next() {
+		t.Fatalf("Next() = false, error %v", s.Err())
+	}
+	if meta := s.Meta(); meta != (kis.ResponseMeta{Status: 200, Resource: "position"}) {
+		t.Errorf("Scanner.Meta() = %+v, want status 200 of resource position", meta)
+	}
+
+	if _, meta, err := k.GetLastPositionByMachineUUIDWithMeta(ctx, "unknown", ""); !errors.Is(err, kis.ErrNotFound) || meta != (kis.ResponseMeta{}) {
+		t.Errorf("GetLastPositionByMachineUUIDWithMeta() = %+v, %v, want empty metadata and ErrNotFound", meta, err)
+	}
+}
@@ -1,17 +1,95 @@
 package kis
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
 // Kubota represents the Kubota API client.
 type Kubota struct {
 	authentication *authentication
+	exec           *executor
+	logger         *slog.Logger
+	tracer         Tracer
+	metrics        MetricsCollector
+	endpoint       string
+	apiPath        string
+}
+
+// NewKIS creates a new Kubota API client. Unless WithLazyAuthentication is given,
+// an access token is requested right away to verify the keys.
+func NewKIS(publicKey, SecretKey, Endpoint string, opts ...Option) (*Kubota, error) {
+	return NewKISContext(context.Background(), publicKey, SecretKey, Endpoint, opts...)
 }
 
-// NewKIS creates a new Kubota API client.
-func NewKIS(publicKey, SecretKey, Endpoint string) (*Kubota, error) {
-	k := &Kubota{}
-	auth, err := newAuthentication(publicKey, SecretKey, Endpoint)
+// NewKISContext is like NewKIS but uses ctx to bound the initial token request.
+func NewKISContext(ctx context.Context, publicKey, SecretKey, Endpoint string, opts ...Option) (*Kubota, error) {
+	cfg := newConfig(opts)
+	endpoint, err := validateEndpoint(Endpoint)
 	if err != nil {
 		return nil, err
 	}
-	k.authentication = auth
+	if cfg.tokenSource == nil && (publicKey == "" || SecretKey == "") {
+		return nil, errors.New("error with authentication: public and secret key are required")
+	}
+	k := &Kubota{
+		exec:     cfg.executor(),
+		logger:   cfg.logger,
+		tracer:   cfg.tracer,
+		metrics:  cfg.metrics,
+		endpoint: endpoint,
+		apiPath:  cfg.apiPath,
+	}
+	source := cfg.tokenSource
+	if source == nil {
+		source = &keyPairTokenSource{
+			PublicKey: publicKey,
+			SecretKey: SecretKey,
+			Endpoint:  endpoint,
+			exec:      k.exec,
+			apiPath:   k.apiPath,
+		}
+	}
+	if cfg.tokenCacheFile != "" {
+		source = FileTokenSource(cfg.tokenCacheFile, source)
+	}
+	k.authentication = newAuthentication(source, cfg.logger, cfg.metrics)
+	if cfg.lazyAuthentication {
+		return k, nil
+	}
+	if err := k.Authenticate(ctx); err != nil {
+		k.Close()
+		return nil, err
+	}
 	return k, nil
 }
+
+// validateEndpoint checks that the endpoint is an absolute http(s) URL and strips a trailing slash.
+func validateEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("error parsing endpoint URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("error parsing endpoint URL: %q is not an absolute http(s) URL", endpoint)
+	}
+	return strings.TrimRight(endpoint, "/"), nil
+}
+
+// Authenticate makes sure the client holds a valid access token and requests one if needed.
+// It can be used as a readiness check, in particular together with WithLazyAuthentication.
+func (k *Kubota) Authenticate(ctx context.Context) error {
+	_, err := k.authentication.token(ctx)
+	return err
+}
+
+// Close stops all background work of the client, like a running token request.
+// Calls after Close return ErrClientClosed.
+func (k *Kubota) Close() error {
+	k.authentication.close()
+	return nil
+}
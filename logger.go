@@ -0,0 +1,27 @@
+package kis
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger sets the logger for structured events like token refreshes, retries,
+// rate limit waits and API errors. By default nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(cfg *config) {
+		cfg.logger = l
+	}
+}
+
+// discardHandler is a slog.Handler which drops all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// newDiscardLogger returns a logger which drops all records.
+func newDiscardLogger() *slog.Logger {
+	return slog.New(discardHandler{})
+}
@@ -1,12 +1,6 @@
 package kis
 
-import (
-	"encoding/json"
-	"fmt"
-	"strings"
-
-	"net/http"
-)
+import "context"
 
 // Machine represents the Machine information returned by the Kubota API.
 type Machine struct {
@@ -27,68 +21,51 @@ type Machine struct {
 
 // GetMachineByMobilePhone retrieves machine information by mobile phone number.
 func (k *Kubota) GetMachineByMobilePhone(mobilePhone string, subscription string) (Machine, error) {
-	return k.getMachine("mobilePhone", mobilePhone, subscription)
+	return payload(k.getMachine(context.Background(), "mobilePhone", mobilePhone, subscription))
+}
+
+// GetMachineByMobilePhoneContext is like GetMachineByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetMachineByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (Machine, error) {
+	return payload(k.getMachine(ctx, "mobilePhone", mobilePhone, subscription))
+}
+
+// GetMachineByMobilePhoneWithMeta is like GetMachineByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetMachineByMobilePhoneWithMeta(ctx context.Context, mobilePhone string, subscription string) (Machine, ResponseMeta, error) {
+	return k.getMachine(ctx, "mobilePhone", mobilePhone, subscription)
 }
 
 // GetMachineByUserName retrieves machine information by username.
 func (k *Kubota) GetMachineByUserName(userName string, subscription string) (Machine, error) {
-	return k.getMachine("userName", userName, subscription)
+	return payload(k.getMachine(context.Background(), "userName", userName, subscription))
 }
 
-// GetMachineByMachineUUID retrieves machine information by machine UUID.
-func (k *Kubota) GetMachineByMachineUUID(machineUUID string, subscription string) (Machine, error) {
-	return k.getMachine("machineUUID", machineUUID, subscription)
+// GetMachineByUserNameContext is like GetMachineByUserName but uses ctx to bound the request.
+func (k *Kubota) GetMachineByUserNameContext(ctx context.Context, userName string, subscription string) (Machine, error) {
+	return payload(k.getMachine(ctx, "userName", userName, subscription))
 }
 
-// getMachine is a helper function to retrieve machine information based on a given field.
-func (k *Kubota) getMachine(field, value, subscription string) (Machine, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/machine?%s=%s", k.authentication.Endpoint, field, value)
-	if subscription != "" {
-		apiURL += "&subscription=" + subscription
-	}
-
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return Machine{}, fmt.Errorf("error creating machine request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
+// GetMachineByUserNameWithMeta is like GetMachineByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetMachineByUserNameWithMeta(ctx context.Context, userName string, subscription string) (Machine, ResponseMeta, error) {
+	return k.getMachine(ctx, "userName", userName, subscription)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return Machine{}, fmt.Errorf("error making machine request: %w", err)
-	}
-	defer resp.Body.Close()
+// GetMachineByMachineUUID retrieves machine information by machine UUID.
+func (k *Kubota) GetMachineByMachineUUID(machineUUID string, subscription string) (Machine, error) {
+	return payload(k.getMachine(context.Background(), "machineUUID", machineUUID, subscription))
+}
 
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return Machine{}, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return Machine{}, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
+// GetMachineByMachineUUIDContext is like GetMachineByMachineUUID but uses ctx to bound the request.
+func (k *Kubota) GetMachineByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (Machine, error) {
+	return payload(k.getMachine(ctx, "machineUUID", machineUUID, subscription))
+}
 
-	// Unmarshal the response
-	var machineResponse struct {
-		Status   int     `json:"Status"`
-		Resource string  `json:"Resource"`
-		Payload  Machine `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&machineResponse); err != nil {
-		return Machine{}, fmt.Errorf("error decoding machine response: %w", err)
-	}
+// GetMachineByMachineUUIDWithMeta is like GetMachineByMachineUUIDContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetMachineByMachineUUIDWithMeta(ctx context.Context, machineUUID string, subscription string) (Machine, ResponseMeta, error) {
+	return k.getMachine(ctx, "machineUUID", machineUUID, subscription)
+}
 
-	// Return the machine information
-	return machineResponse.Payload, nil
+// getMachine is a helper function to retrieve machine information based on a given field.
+func (k *Kubota) getMachine(ctx context.Context, field, value, subscription string) (Machine, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetMachine", Endpoint: "machine", Field: field, Subscription: subscription}
+	return do[Machine](ctx, k, call, value)
 }
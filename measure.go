@@ -1,10 +1,7 @@
 package kis
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
+	"context"
 	"time"
 )
 
@@ -20,84 +17,51 @@ type Measure struct {
 
 // GetHistoricalMeasureByMobilePhone retrieves historical measure information by mobile phone number.
 func (k *Kubota) GetHistoricalMeasureByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]Measure, error) {
-	return k.getMeasure("mobilePhone", mobilePhone, subscription, startDate, endDate)
+	return payload(k.getMeasure(context.Background(), "mobilePhone", mobilePhone, subscription, startDate, endDate))
+}
+
+// GetHistoricalMeasureByMobilePhoneContext is like GetHistoricalMeasureByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalMeasureByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Measure, error) {
+	return payload(k.getMeasure(ctx, "mobilePhone", mobilePhone, subscription, startDate, endDate))
+}
+
+// GetHistoricalMeasureByMobilePhoneWithMeta is like GetHistoricalMeasureByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalMeasureByMobilePhoneWithMeta(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Measure, ResponseMeta, error) {
+	return k.getMeasure(ctx, "mobilePhone", mobilePhone, subscription, startDate, endDate)
 }
 
 // GetHistoricalMeasureByUserName retrieves historical measure information by username.
 func (k *Kubota) GetHistoricalMeasureByUserName(userName, subscription string, startDate, endDate time.Time) ([]Measure, error) {
-	return k.getMeasure("userName", userName, subscription, startDate, endDate)
+	return payload(k.getMeasure(context.Background(), "userName", userName, subscription, startDate, endDate))
 }
 
-// GetHistoricalMeasureByMachineUUID retrieves historical measure information by machine UUID.
-func (k *Kubota) GetHistoricalMeasureByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Measure, error) {
-	return k.getMeasure("machineUUID", machineUUID, subscription, startDate, endDate)
+// GetHistoricalMeasureByUserNameContext is like GetHistoricalMeasureByUserName but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalMeasureByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Measure, error) {
+	return payload(k.getMeasure(ctx, "userName", userName, subscription, startDate, endDate))
 }
 
-// getMeasure is a helper function to retrieve measure information based on a given field.
-func (k *Kubota) getMeasure(field, value, subscription string, startDate, endDate time.Time) ([]Measure, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/measure?%s=%s", k.authentication.Endpoint, field, value)
-	if subscription != "" {
-		apiURL += "&subscription=" + subscription
-	}
-	if !startDate.IsZero() {
-		s := CustomTime{startDate}
-		ss, err := s.MarshalJSON()
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling start date: %w", err)
-		}
-		apiURL += "&startDate=" + string(ss)
-	}
-	if !endDate.IsZero() {
-		e := CustomTime{endDate}
-		ee, err := e.MarshalJSON()
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling end date: %w", err)
-		}
-		apiURL += "&endDate=" + string(ee)
-	}
-
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating measure request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
+// GetHistoricalMeasureByUserNameWithMeta is like GetHistoricalMeasureByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalMeasureByUserNameWithMeta(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Measure, ResponseMeta, error) {
+	return k.getMeasure(ctx, "userName", userName, subscription, startDate, endDate)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making measure request: %w", err)
-	}
-	defer resp.Body.Close()
+// GetHistoricalMeasureByMachineUUID retrieves historical measure information by machine UUID.
+func (k *Kubota) GetHistoricalMeasureByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Measure, error) {
+	return payload(k.getMeasure(context.Background(), "machineUUID", machineUUID, subscription, startDate, endDate))
+}
 
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return nil, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return nil, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
+// GetHistoricalMeasureByMachineUUIDContext is like GetHistoricalMeasureByMachineUUID but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalMeasureByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Measure, error) {
+	return payload(k.getMeasure(ctx, "machineUUID", machineUUID, subscription, startDate, endDate))
+}
 
-	// Unmarshal the response
-	var measureResponse struct {
-		Status   int       `json:"Status"`
-		Resource string    `json:"Resource"`
-		Payload  []Measure `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&measureResponse); err != nil {
-		return nil, fmt.Errorf("error decoding measure response: %w", err)
-	}
+// GetHistoricalMeasureByMachineUUIDWithMeta is like GetHistoricalMeasureByMachineUUIDContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalMeasureByMachineUUIDWithMeta(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Measure, ResponseMeta, error) {
+	return k.getMeasure(ctx, "machineUUID", machineUUID, subscription, startDate, endDate)
+}
 
-	// Return the measure information
-	return measureResponse.Payload, nil
+// getMeasure is a helper function to retrieve measure information based on a given field.
+func (k *Kubota) getMeasure(ctx context.Context, field, value, subscription string, startDate, endDate time.Time) ([]Measure, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetHistoricalMeasure", Endpoint: "measure", Field: field, Subscription: subscription, StartDate: startDate, EndDate: endDate}
+	return do[[]Measure](ctx, k, call, value)
 }
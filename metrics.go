@@ -0,0 +1,50 @@
+package kis
+
+import (
+	"io"
+	"time"
+)
+
+// MetricsCollector receives usage metrics of the client. Its methods are called concurrently.
+// The kisprom package provides a Prometheus implementation.
+type MetricsCollector interface {
+	// ObserveRequest is called after every HTTP attempt with the status code of the response,
+	// or 0 if the request failed without a response.
+	ObserveRequest(endpoint string, status int, latency time.Duration)
+	// ObserveRetry is called before a failed request to endpoint is retried.
+	ObserveRetry(endpoint string)
+	// ObserveRateLimitWait is called when a request to endpoint was delayed by the client-side rate limiter.
+	ObserveRateLimitWait(endpoint string, wait time.Duration)
+	// ObserveTokenRefresh is called after every token refresh with its error, nil on success.
+	ObserveTokenRefresh(err error)
+	// ObservePayload is called with the number of items and the size in bytes of every decoded response body.
+	ObservePayload(endpoint string, items int, bytes int64)
+}
+
+// WithMetrics sets the collector for the usage metrics of the client.
+func WithMetrics(m MetricsCollector) Option {
+	return func(cfg *config) {
+		cfg.metrics = m
+	}
+}
+
+// noopMetrics is the default collector which drops all metrics.
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveRequest(string, int, time.Duration)  {}
+func (noopMetrics) ObserveRetry(string)                        {}
+func (noopMetrics) ObserveRateLimitWait(string, time.Duration) {}
+func (noopMetrics) ObserveTokenRefresh(error)                  {}
+func (noopMetrics) ObservePayload(string, int, int64)          {}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,71 @@
+package kis
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Doer sends an HTTP request and returns the response, *http.Client satisfies it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc is a function which satisfies the Doer interface.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do implements the Doer interface.
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the Doer which sends the requests of the client, e.g. to inject headers,
+// log requests or collect timings. It sees every attempt of every request including the token request.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware adds middlewares to the client. The first middleware is the outermost one.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(cfg *config) {
+		cfg.middlewares = append(cfg.middlewares, mw...)
+	}
+}
+
+// RequestInfo describes a request of the client. Middlewares get it with RequestInfoFromContext.
+type RequestInfo struct {
+	// Endpoint is "token" for the token request or the name of the resource, e.g. "position" or "measure".
+	Endpoint string
+	// Field is the lookup field of a resource request, i.e. "machineUUID", "userName" or "mobilePhone".
+	Field string
+	// Attempt is the number of the attempt, starting at 1 and increased by retries.
+	Attempt int
+}
+
+// requestInfoKey is the context key of the RequestInfo of a request.
+type requestInfoKey struct{}
+
+// RequestInfoFromContext returns the RequestInfo attached to the context of a request sent by the client.
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
+
+// lookupFields are the query parameters used to look up resources.
+var lookupFields = []string{"machineUUID", "userName", "mobilePhone"}
+
+// lookupField returns the lookup field of a resource query.
+func lookupField(query url.Values) string {
+	for _, f := range lookupFields {
+		if query.Has(f) {
+			return f
+		}
+	}
+	return ""
+}
+
+// chain wraps d with the middlewares, the first middleware being the outermost.
+func chain(d Doer, mw []Middleware) Doer {
+	for i := len(mw) - 1; i >= 0; i-- {
+		d = mw[i](d)
+	}
+	return d
+}
@@ -0,0 +1,139 @@
+package kis
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAPIPath is the path prefix of the KIS API version this package is built against.
+	defaultAPIPath = "/api/v1"
+	// defaultUserAgent is sent with every request unless overridden by WithUserAgent.
+	defaultUserAgent = "go-kubota-kis-api"
+)
+
+// config holds the settings collected from the options passed to NewKIS.
+type config struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	userAgent  string
+	apiPath    string
+	retry      RetryPolicy
+
+	limiter          *limiter
+	endpointLimiters map[string]*limiter
+	onRateLimitWait  func(endpoint string, wait time.Duration)
+
+	tokenSource    TokenSource
+	tokenCacheFile string
+
+	lazyAuthentication bool
+
+	middlewares []Middleware
+	logger      *slog.Logger
+	tracer      Tracer
+	metrics     MetricsCollector
+}
+
+// Option configures a Kubota client created by NewKIS.
+type Option func(*config)
+
+// WithHTTPClient sets the http.Client used for all requests. The client is copied,
+// so options like WithTimeout or WithTransport do not modify the caller's instance.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cfg *config) {
+		cfg.httpClient = c
+	}
+}
+
+// WithTransport sets the http.RoundTripper used by the client, e.g. for proxies or custom TLS settings.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(cfg *config) {
+		cfg.transport = rt
+	}
+}
+
+// WithTimeout sets the overall timeout of a single HTTP request.
+func WithTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.timeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(cfg *config) {
+		cfg.userAgent = ua
+	}
+}
+
+// WithAPIVersionPath sets the path prefix of the API, which defaults to "/api/v1".
+func WithAPIVersionPath(p string) Option {
+	return func(cfg *config) {
+		cfg.apiPath = "/" + strings.Trim(p, "/")
+	}
+}
+
+// WithLazyAuthentication defers the first token request of NewKIS until the first API call
+// or an explicit call to Authenticate, so the client can be created without network access.
+// The endpoint and keys are still validated.
+func WithLazyAuthentication() Option {
+	return func(cfg *config) {
+		cfg.lazyAuthentication = true
+	}
+}
+
+// newConfig applies the options on top of the defaults.
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		userAgent: defaultUserAgent,
+		apiPath:   defaultAPIPath,
+		retry:     DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.logger == nil {
+		cfg.logger = newDiscardLogger()
+	}
+	if cfg.tracer == nil {
+		cfg.tracer = noopTracer{}
+	}
+	if cfg.metrics == nil {
+		cfg.metrics = noopMetrics{}
+	}
+	return cfg
+}
+
+// client returns the http.Client shared by all endpoints of a Kubota client.
+func (cfg *config) client() *http.Client {
+	c := &http.Client{}
+	if cfg.httpClient != nil {
+		*c = *cfg.httpClient
+	}
+	if cfg.transport != nil {
+		c.Transport = cfg.transport
+	}
+	if cfg.timeout > 0 {
+		c.Timeout = cfg.timeout
+	}
+	return c
+}
+
+// executor returns the executor for the requests of a Kubota client.
+func (cfg *config) executor() *executor {
+	return &executor{
+		doer:      chain(cfg.client(), cfg.middlewares),
+		userAgent: cfg.userAgent,
+		retry:     cfg.retry,
+
+		limiter:          cfg.limiter,
+		endpointLimiters: cfg.endpointLimiters,
+		onRateLimitWait:  cfg.onRateLimitWait,
+		logger:           cfg.logger,
+		metrics:          cfg.metrics,
+	}
+}
@@ -0,0 +1,37 @@
+package kis
+
+import (
+	"context"
+	"sync"
+)
+
+// parallel calls fn for the indexes 0 to n-1, at most concurrency at a time, and returns the error of
+// every index. The calls are started in index order; once ctx is done, the remaining indexes fail with
+// its error without fn being called.
+func parallel(ctx context.Context, n, concurrency int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+			// select picks randomly if ctx is done as well
+			if err := ctx.Err(); err != nil {
+				<-sem
+				errs[i] = err
+				continue
+			}
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
@@ -1,10 +1,7 @@
 package kis
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
+	"context"
 	"time"
 )
 
@@ -21,150 +18,102 @@ type Position struct {
 
 // GetLastPositionByMobilePhone retrieves the last position information by mobile phone number.
 func (k *Kubota) GetLastPositionByMobilePhone(mobilePhone string, subscription string) (*Position, error) {
-	return k.getPosition("mobilePhone", mobilePhone, subscription)
+	return payload(k.getPosition(context.Background(), "mobilePhone", mobilePhone, subscription))
+}
+
+// GetLastPositionByMobilePhoneContext is like GetLastPositionByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetLastPositionByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (*Position, error) {
+	return payload(k.getPosition(ctx, "mobilePhone", mobilePhone, subscription))
+}
+
+// GetLastPositionByMobilePhoneWithMeta is like GetLastPositionByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetLastPositionByMobilePhoneWithMeta(ctx context.Context, mobilePhone string, subscription string) (*Position, ResponseMeta, error) {
+	return k.getPosition(ctx, "mobilePhone", mobilePhone, subscription)
 }
 
 // GetLastPositionByUserName retrieves the last position information by username.
 func (k *Kubota) GetLastPositionByUserName(userName string, subscription string) (*Position, error) {
-	return k.getPosition("userName", userName, subscription)
+	return payload(k.getPosition(context.Background(), "userName", userName, subscription))
+}
+
+// GetLastPositionByUserNameContext is like GetLastPositionByUserName but uses ctx to bound the request.
+func (k *Kubota) GetLastPositionByUserNameContext(ctx context.Context, userName string, subscription string) (*Position, error) {
+	return payload(k.getPosition(ctx, "userName", userName, subscription))
+}
+
+// GetLastPositionByUserNameWithMeta is like GetLastPositionByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetLastPositionByUserNameWithMeta(ctx context.Context, userName string, subscription string) (*Position, ResponseMeta, error) {
+	return k.getPosition(ctx, "userName", userName, subscription)
 }
 
 // GetLastPositionByMachineUUID retrieves the last position information by machine UUID.
 func (k *Kubota) GetLastPositionByMachineUUID(machineUUID string, subscription string) (*Position, error) {
-	return k.getPosition("machineUUID", machineUUID, subscription)
+	return payload(k.getPosition(context.Background(), "machineUUID", machineUUID, subscription))
+}
+
+// GetLastPositionByMachineUUIDContext is like GetLastPositionByMachineUUID but uses ctx to bound the request.
+func (k *Kubota) GetLastPositionByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (*Position, error) {
+	return payload(k.getPosition(ctx, "machineUUID", machineUUID, subscription))
+}
+
+// GetLastPositionByMachineUUIDWithMeta is like GetLastPositionByMachineUUIDContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetLastPositionByMachineUUIDWithMeta(ctx context.Context, machineUUID string, subscription string) (*Position, ResponseMeta, error) {
+	return k.getPosition(ctx, "machineUUID", machineUUID, subscription)
 }
 
 // GetHistoricalPositionByMobilePhone retrieves historical position information by mobile phone number.
 func (k *Kubota) GetHistoricalPositionByMobilePhone(mobilePhone, subscription string, startDate, endDate time.Time) ([]Position, error) {
-	return k.getPositions("mobilePhone", mobilePhone, subscription, startDate, endDate)
+	return payload(k.getPositions(context.Background(), "mobilePhone", mobilePhone, subscription, startDate, endDate))
+}
+
+// GetHistoricalPositionByMobilePhoneContext is like GetHistoricalPositionByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalPositionByMobilePhoneContext(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Position, error) {
+	return payload(k.getPositions(ctx, "mobilePhone", mobilePhone, subscription, startDate, endDate))
+}
+
+// GetHistoricalPositionByMobilePhoneWithMeta is like GetHistoricalPositionByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalPositionByMobilePhoneWithMeta(ctx context.Context, mobilePhone, subscription string, startDate, endDate time.Time) ([]Position, ResponseMeta, error) {
+	return k.getPositions(ctx, "mobilePhone", mobilePhone, subscription, startDate, endDate)
 }
 
 // GetHistoricalPositionByUserName retrieves historical position information by username.
 func (k *Kubota) GetHistoricalPositionByUserName(userName, subscription string, startDate, endDate time.Time) ([]Position, error) {
-	return k.getPositions("userName", userName, subscription, startDate, endDate)
+	return payload(k.getPositions(context.Background(), "userName", userName, subscription, startDate, endDate))
+}
+
+// GetHistoricalPositionByUserNameContext is like GetHistoricalPositionByUserName but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalPositionByUserNameContext(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Position, error) {
+	return payload(k.getPositions(ctx, "userName", userName, subscription, startDate, endDate))
+}
+
+// GetHistoricalPositionByUserNameWithMeta is like GetHistoricalPositionByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalPositionByUserNameWithMeta(ctx context.Context, userName, subscription string, startDate, endDate time.Time) ([]Position, ResponseMeta, error) {
+	return k.getPositions(ctx, "userName", userName, subscription, startDate, endDate)
 }
 
 // GetHistoricalPositionByMachineUUID retrieves historical position information by machine UUID.
 func (k *Kubota) GetHistoricalPositionByMachineUUID(machineUUID, subscription string, startDate, endDate time.Time) ([]Position, error) {
-	return k.getPositions("machineUUID", machineUUID, subscription, startDate, endDate)
+	return payload(k.getPositions(context.Background(), "machineUUID", machineUUID, subscription, startDate, endDate))
 }
 
-// getPosition is a helper function to retrieve position information based on a given field.
-func (k *Kubota) getPosition(field, value, subscription string) (*Position, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/position?%s=%s", k.authentication.Endpoint, field, value)
-	if subscription != "" {
-		apiURL += "&subscription=" + subscription
-	}
-
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return &Position{}, fmt.Errorf("error creating position request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return &Position{}, fmt.Errorf("error making position request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return nil, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return nil, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
-
-	// Unmarshal the response
-	var positionResponse struct {
-		Status   int       `json:"Status"`
-		Resource string    `json:"Resource"`
-		Payload  *Position `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&positionResponse); err != nil {
-		return &Position{}, fmt.Errorf("error decoding position response: %w", err)
-	}
-
-	// Return the position information
-	return positionResponse.Payload, nil
+// GetHistoricalPositionByMachineUUIDContext is like GetHistoricalPositionByMachineUUID but uses ctx to bound the request.
+func (k *Kubota) GetHistoricalPositionByMachineUUIDContext(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Position, error) {
+	return payload(k.getPositions(ctx, "machineUUID", machineUUID, subscription, startDate, endDate))
+}
+
+// GetHistoricalPositionByMachineUUIDWithMeta is like GetHistoricalPositionByMachineUUIDContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetHistoricalPositionByMachineUUIDWithMeta(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time) ([]Position, ResponseMeta, error) {
+	return k.getPositions(ctx, "machineUUID", machineUUID, subscription, startDate, endDate)
 }
 
 // getPosition is a helper function to retrieve position information based on a given field.
-func (k *Kubota) getPositions(field, value, subscription string, startDate, endDate time.Time) ([]Position, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/position?%s=%s", k.authentication.Endpoint, field, value)
-	if subscription != "" {
-		apiURL += "&subscription=" + subscription
-	}
-	if !startDate.IsZero() {
-		s := CustomTime{startDate}
-		ss, err := s.MarshalJSON()
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling start date: %w", err)
-		}
-		apiURL += "&startDate=" + string(ss)
-	}
-	if !endDate.IsZero() {
-		e := CustomTime{endDate}
-		ee, err := e.MarshalJSON()
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling end date: %w", err)
-		}
-		apiURL += "&endDate=" + string(ee)
-	}
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating position request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making position request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return nil, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return nil, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
-	// Unmarshal the response
-	var positionResponse struct {
-		Status   int        `json:"Status"`
-		Resource string     `json:"Resource"`
-		Payload  []Position `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&positionResponse); err != nil {
-		return nil, fmt.Errorf("error decoding position response: %w", err)
-	}
-
-	// Return the position information
-	return positionResponse.Payload, nil
+func (k *Kubota) getPosition(ctx context.Context, field, value, subscription string) (*Position, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetLastPosition", Endpoint: "position", Field: field, Subscription: subscription}
+	return do[*Position](ctx, k, call, value)
+}
+
+// getPositions is a helper function to retrieve historical position information based on a given field.
+func (k *Kubota) getPositions(ctx context.Context, field, value, subscription string, startDate, endDate time.Time) ([]Position, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetHistoricalPosition", Endpoint: "position", Field: field, Subscription: subscription, StartDate: startDate, EndDate: endDate}
+	return do[[]Position](ctx, k, call, value)
 }
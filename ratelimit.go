@@ -0,0 +1,98 @@
+package kis
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRateLimit throttles all outgoing requests of the client, including token requests,
+// to rps requests per second with bursts of up to burst requests.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(cfg *config) {
+		cfg.limiter = newLimiter(rps, burst)
+	}
+}
+
+// WithEndpointRateLimit throttles requests to a single endpoint like "position" or "measure".
+// It applies in addition to the limit set by WithRateLimit.
+func WithEndpointRateLimit(endpoint string, rps float64, burst int) Option {
+	return func(cfg *config) {
+		if cfg.endpointLimiters == nil {
+			cfg.endpointLimiters = make(map[string]*limiter)
+		}
+		cfg.endpointLimiters[endpoint] = newLimiter(rps, burst)
+	}
+}
+
+// WithRateLimitObserver sets a function which is called with the time a request
+// to endpoint was queued by the rate limiter. It is only called if a request had to wait.
+func WithRateLimitObserver(fn func(endpoint string, wait time.Duration)) Option {
+	return func(cfg *config) {
+		cfg.onRateLimitWait = fn
+	}
+}
+
+// limiter is a token bucket rate limiter safe for concurrent use.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns a limiter with a full bucket, or nil if rps is not positive.
+func newLimiter(rps float64, burst int) *limiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket and returns how long the caller has to wait for it.
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token to the bucket.
+func (l *limiter) cancel() {
+	l.mu.Lock()
+	l.tokens++
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.mu.Unlock()
+}
+
+// wait blocks until a token is available or ctx is done and returns the time spent waiting.
+func (l *limiter) wait(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	d := l.reserve(time.Now())
+	if err := sleep(ctx, d); err != nil {
+		l.cancel()
+		return d, err
+	}
+	return d, nil
+}
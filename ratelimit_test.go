@@ -0,0 +1,83 @@
+package kis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	l := newLimiter(10, 3)
+	now := l.last
+	steps := []struct {
+		name  string
+		after time.Duration
+		want  time.Duration
+	}{
+		{"burst 1", 0, 0},
+		{"burst 2", 0, 0},
+		{"burst 3", 0, 0},
+		{"bucket empty", 0, 100 * time.Millisecond},
+		{"queued behind the previous one", 0, 200 * time.Millisecond},
+		// 500 ms refill 5 tokens, 2 of which were owed
+		{"refilled", 500 * time.Millisecond, 0},
+		{"refilled 2", 0, 0},
+		{"refilled 3", 0, 0},
+		{"refill capped at burst", 0, 100 * time.Millisecond},
+		{"refill after a long pause", time.Hour, 0},
+	}
+	for _, s := range steps {
+		now = now.Add(s.after)
+		if got := l.reserve(now); !near(got.Seconds(), s.want.Seconds(), 1e-6) {
+			t.Errorf("%s: reserve() = %v, want %v", s.name, got, s.want)
+		}
+	}
+}
+
+func TestLimiterLongPauseKeepsBurst(t *testing.T) {
+	l := newLimiter(1, 2)
+	now := l.last.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(now); d != 0 {
+			t.Fatalf("reserve() %d = %v, want 0", i, d)
+		}
+	}
+	if d := l.reserve(now); !near(d.Seconds(), 1, 1e-6) {
+		t.Errorf("reserve() after the burst = %v, want 1s", d)
+	}
+}
+
+func TestLimiterWaitCanceledReturnsToken(t *testing.T) {
+	l := newLimiter(1, 1)
+	if d, err := l.wait(context.Background()); d != 0 || err != nil {
+		t.Fatalf("wait() = %v, %v, want no wait", d, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	d, err := l.wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait() error = %v, want context.DeadlineExceeded", err)
+	}
+	if d < 900*time.Millisecond || time.Since(begin) > 500*time.Millisecond {
+		t.Errorf("wait() = %v after %v, want a reservation of about 1s given up at the deadline", d, time.Since(begin))
+	}
+	// the canceled reservation is not owed, so the next caller waits for one token only
+	if d := l.reserve(time.Now()); d > time.Second {
+		t.Errorf("reserve() after the canceled wait = %v, want at most 1s", d)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	if l := newLimiter(0, 5); l != nil {
+		t.Fatalf("newLimiter(0, 5) = %+v, want nil", l)
+	}
+	var l *limiter
+	if d, err := l.wait(context.Background()); d != 0 || err != nil {
+		t.Errorf("wait() of nil limiter = %v, %v", d, err)
+	}
+	if l := newLimiter(1, 0); l.burst != 1 {
+		t.Errorf("burst = %v, want at least 1", l.burst)
+	}
+}
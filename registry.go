@@ -1,11 +1,6 @@
 package kis
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
-)
+import "context"
 
 // Registry represents the Registry information returned by the Kubota API.
 type Registry struct {
@@ -21,68 +16,51 @@ type Registry struct {
 
 // GetRegistryByMobilePhone retrieves registry information by mobile phone number.
 func (k *Kubota) GetRegistryByMobilePhone(mobilePhone string, subscription string) (Registry, error) {
-	return k.getRegistry("mobilePhone", mobilePhone, subscription)
+	return payload(k.getRegistry(context.Background(), "mobilePhone", mobilePhone, subscription))
+}
+
+// GetRegistryByMobilePhoneContext is like GetRegistryByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetRegistryByMobilePhoneContext(ctx context.Context, mobilePhone string, subscription string) (Registry, error) {
+	return payload(k.getRegistry(ctx, "mobilePhone", mobilePhone, subscription))
+}
+
+// GetRegistryByMobilePhoneWithMeta is like GetRegistryByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetRegistryByMobilePhoneWithMeta(ctx context.Context, mobilePhone string, subscription string) (Registry, ResponseMeta, error) {
+	return k.getRegistry(ctx, "mobilePhone", mobilePhone, subscription)
 }
 
 // GetRegistryByUserName retrieves registry information by username.
 func (k *Kubota) GetRegistryByUserName(userName string, subscription string) (Registry, error) {
-	return k.getRegistry("userName", userName, subscription)
+	return payload(k.getRegistry(context.Background(), "userName", userName, subscription))
 }
 
-// GetRegistryByMachineUUID retrieves registry information by machine UUID.
-func (k *Kubota) GetRegistryByMachineUUID(machineUUID string, subscription string) (Registry, error) {
-	return k.getRegistry("machineUUID", machineUUID, subscription)
+// GetRegistryByUserNameContext is like GetRegistryByUserName but uses ctx to bound the request.
+func (k *Kubota) GetRegistryByUserNameContext(ctx context.Context, userName string, subscription string) (Registry, error) {
+	return payload(k.getRegistry(ctx, "userName", userName, subscription))
 }
 
-// getRegistry is a helper function to retrieve registry information based on a given field.
-func (k *Kubota) getRegistry(field, value, subscription string) (Registry, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/registry?%s=%s", k.authentication.Endpoint, field, value)
-	if subscription != "" {
-		apiURL += "&subscription=" + subscription
-	}
-
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return Registry{}, fmt.Errorf("error creating registry request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
+// GetRegistryByUserNameWithMeta is like GetRegistryByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetRegistryByUserNameWithMeta(ctx context.Context, userName string, subscription string) (Registry, ResponseMeta, error) {
+	return k.getRegistry(ctx, "userName", userName, subscription)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return Registry{}, fmt.Errorf("error making registry request: %w", err)
-	}
-	defer resp.Body.Close()
+// GetRegistryByMachineUUID retrieves registry information by machine UUID.
+func (k *Kubota) GetRegistryByMachineUUID(machineUUID string, subscription string) (Registry, error) {
+	return payload(k.getRegistry(context.Background(), "machineUUID", machineUUID, subscription))
+}
 
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return Registry{}, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return Registry{}, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
+// GetRegistryByMachineUUIDContext is like GetRegistryByMachineUUID but uses ctx to bound the request.
+func (k *Kubota) GetRegistryByMachineUUIDContext(ctx context.Context, machineUUID string, subscription string) (Registry, error) {
+	return payload(k.getRegistry(ctx, "machineUUID", machineUUID, subscription))
+}
 
-	// Unmarshal the response
-	var registryResponse struct {
-		Status   int      `json:"Status"`
-		Resource string   `json:"Resource"`
-		Payload  Registry `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&registryResponse); err != nil {
-		return Registry{}, fmt.Errorf("error decoding registry response: %w", err)
-	}
+// GetRegistryByMachineUUIDWithMeta is like GetRegistryByMachineUUIDContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetRegistryByMachineUUIDWithMeta(ctx context.Context, machineUUID string, subscription string) (Registry, ResponseMeta, error) {
+	return k.getRegistry(ctx, "machineUUID", machineUUID, subscription)
+}
 
-	// Return the registry information
-	return registryResponse.Payload, nil
+// getRegistry is a helper function to retrieve registry information based on a given field.
+func (k *Kubota) getRegistry(ctx context.Context, field, value, subscription string) (Registry, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetRegistry", Endpoint: "registry", Field: field, Subscription: subscription}
+	return do[Registry](ctx, k, call, value)
 }
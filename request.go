@@ -0,0 +1,163 @@
+package kis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ResponseMeta holds the metadata of the envelope of an API response.
+type ResponseMeta struct {
+	Status   int    `json:"Status"`
+	Resource string `json:"Resource"`
+}
+
+// envelope is the body of every successful response of the Kubota API.
+type envelope[T any] struct {
+	ResponseMeta
+	Payload T `json:"Payload"`
+}
+
+// do requests the resource of call, looked up by value, and returns the decoded payload and the
+// metadata of the response. It is the common pipeline of all resource helpers.
+func do[T any](ctx context.Context, k *Kubota, call CallInfo, value string) (T, ResponseMeta, error) {
+	var env envelope[T]
+	r, err := k.begin(ctx, call, value)
+	if err != nil {
+		return env.Payload, env.ResponseMeta, err
+	}
+
+	// Unmarshal the response
+	if err := json.NewDecoder(r.body).Decode(&env); err != nil {
+		return env.Payload, env.ResponseMeta, r.finish(0, fmt.Errorf("error decoding %s response: %w", call.Endpoint, err))
+	}
+	return env.Payload, env.ResponseMeta, r.finish(payloadItems(env.Payload), nil)
+}
+
+// payload drops the metadata of a resource helper result, for the getters returning only the payload.
+func payload[T any](v T, _ ResponseMeta, err error) (T, error) {
+	return v, err
+}
+
+// response is a successful response of the pipeline whose body is being decoded.
+type response struct {
+	k      *Kubota
+	ctx    context.Context
+	call   CallInfo
+	span   Span
+	start  time.Time
+	status int
+	resp   *http.Response
+	body   *countingReader
+}
+
+// begin starts the span of call and sends the request. Unless an error is returned, the body of the
+// successful response has to be decoded from the returned response, followed by a call to finish.
+func (k *Kubota) begin(ctx context.Context, call CallInfo, value string) (*response, error) {
+	r := &response{k: k, call: call, start: time.Now()}
+	r.ctx, r.span = k.tracer.Start(ctx, call)
+	resource := call.Endpoint
+	query := withDateRange(newQuery(call.Field, value, call.Subscription), call.StartDate, call.EndDate)
+	// Make the request
+	resp, err := k.send(r.ctx, resource, query)
+	if err != nil {
+		k.logger.LogAttrs(r.ctx, slog.LevelError, "request failed",
+			slog.String("endpoint", resource), slog.Any("error", err), slog.Duration("latency", time.Since(r.start)))
+		err = fmt.Errorf("error making %s request: %w", resource, err)
+		r.span.End(CallResult{Duration: time.Since(r.start), Err: err})
+		return nil, err
+	}
+
+	// Handle the response
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		apiErr := newAPIError(resp)
+		k.logger.LogAttrs(r.ctx, slog.LevelWarn, "API error",
+			slog.String("endpoint", resource), slog.Int("status", apiErr.Status), slog.String("log_id", apiErr.LogID),
+			slog.String("title", apiErr.Title), slog.Duration("latency", time.Since(r.start)))
+		r.span.End(CallResult{Status: resp.StatusCode, LogID: apiErr.LogID, Duration: time.Since(r.start), Err: apiErr})
+		return nil, apiErr
+	}
+	r.status = resp.StatusCode
+	r.resp = resp
+	r.body = &countingReader{r: resp.Body}
+	return r, nil
+}
+
+// finish closes the response and ends the span with the number of decoded items and the decoding error.
+// It returns err.
+func (r *response) finish(items int, err error) error {
+	r.resp.Body.Close()
+	if err == nil {
+		r.k.metrics.ObservePayload(r.call.Endpoint, items, r.body.n)
+		r.k.logger.LogAttrs(r.ctx, slog.LevelDebug, "request completed",
+			slog.String("endpoint", r.call.Endpoint), slog.Int("status", r.status), slog.Int("items", items),
+			slog.Duration("latency", time.Since(r.start)))
+	}
+	r.span.End(CallResult{Status: r.status, Items: items, Duration: time.Since(r.start), Err: err})
+	return err
+}
+
+// newQuery builds the query parameters of a resource request. The lookup field is one of
+// mobilePhone, userName or machineUUID; an empty subscription is omitted.
+func newQuery(field, value, subscription string) url.Values {
+	query := url.Values{}
+	query.Set(field, value)
+	if subscription != "" {
+		query.Set("subscription", subscription)
+	}
+	return query
+}
+
+// withDateRange adds the startDate and endDate parameters to query, zero dates are omitted.
+func withDateRange(query url.Values, startDate, endDate time.Time) url.Values {
+	if !startDate.IsZero() {
+		query.Set("startDate", formatTime(startDate))
+	}
+	if !endDate.IsZero() {
+		query.Set("endDate", formatTime(endDate))
+	}
+	return query
+}
+
+// send makes a GET request to the given resource. If the API rejects the access token,
+// the client re-authenticates and sends the request once more.
+func (k *Kubota) send(ctx context.Context, resource string, query url.Values) (*http.Response, error) {
+	for reauthenticated := false; ; reauthenticated = true {
+		var token string
+		info := RequestInfo{Endpoint: resource, Field: lookupField(query)}
+		resp, err := k.exec.do(ctx, info, func() (*http.Request, error) {
+			var err error
+			if token, err = k.authentication.token(ctx); err != nil {
+				return nil, err
+			}
+			return k.newRequest(ctx, token, resource, query)
+		})
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || reauthenticated {
+			return resp, err
+		}
+		resp.Body.Close()
+		k.authentication.invalidate(token)
+	}
+}
+
+// newRequest creates a GET request for the given resource with the common headers set.
+func (k *Kubota) newRequest(ctx context.Context, token, resource string, query url.Values) (*http.Request, error) {
+	u, err := url.Parse(k.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u = u.JoinPath(k.apiPath, resource)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req, nil
+}
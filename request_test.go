@@ -0,0 +1,95 @@
+package kis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewRequestQuery(t *testing.T) {
+	k := &Kubota{endpoint: "https://kis.example.com", apiPath: defaultAPIPath}
+	tests := []struct {
+		name  string
+		field string
+		value string
+	}{
+		{"plus", "mobilePhone", "+6591234567"},
+		{"ampersand", "userName", "a&b"},
+		{"space", "userName", "john doe"},
+		{"non-ASCII", "userName", "jürgen.müller@bauernhof.de"},
+		{"CJK", "userName", "久保田"},
+		{"equals and hash", "userName", "a=b#c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := k.newRequest(context.Background(), "token", "user", newQuery(tt.field, tt.value, "sub&1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := req.URL.Query()
+			if got := q.Get(tt.field); got != tt.value {
+				t.Errorf("%s = %q, want %q (raw query %q)", tt.field, got, tt.value, req.URL.RawQuery)
+			}
+			if got := q.Get("subscription"); got != "sub&1" {
+				t.Errorf("subscription = %q, want %q", got, "sub&1")
+			}
+			if len(q) != 2 {
+				t.Errorf("query has %d parameters, want 2: %q", len(q), req.URL.RawQuery)
+			}
+			if req.URL.Path != "/api/v1/user" {
+				t.Errorf("path = %q, want /api/v1/user", req.URL.Path)
+			}
+		})
+	}
+}
+
+func TestNewRequestOmitsEmptySubscription(t *testing.T) {
+	k := &Kubota{endpoint: "https://kis.example.com", apiPath: defaultAPIPath}
+	req, err := k.newRequest(context.Background(), "token", "machine", newQuery("machineUUID", "m1", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.RawQuery != "machineUUID=m1" {
+		t.Errorf("raw query = %q, want machineUUID=m1", req.URL.RawQuery)
+	}
+}
+
+func TestNewRequestDateRange(t *testing.T) {
+	k := &Kubota{endpoint: "https://kis.example.com", apiPath: defaultAPIPath}
+	berlin := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		name      string
+		start     time.Time
+		end       time.Time
+		wantStart string
+		wantEnd   string
+	}{
+		{"UTC", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC), "2024-03-01T00:00:00", "2024-03-31T23:59:59"},
+		{"converted to UTC", time.Date(2024, 6, 1, 8, 30, 0, 0, berlin), time.Date(2024, 6, 2, 1, 0, 0, 0, berlin), "2024-06-01T06:30:00", "2024-06-01T23:00:00"},
+		{"fractional seconds dropped", time.Date(2024, 1, 2, 3, 4, 5, 999, time.UTC), time.Date(2024, 1, 2, 4, 4, 5, 0, time.UTC), "2024-01-02T03:04:05", "2024-01-02T04:04:05"},
+		{"zero start omitted", time.Time{}, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "", "2024-01-02T00:00:00"},
+		{"zero end omitted", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}, "2024-01-01T00:00:00", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := withDateRange(newQuery("machineUUID", "m1", ""), tt.start, tt.end)
+			req, err := k.newRequest(context.Background(), "token", "position", query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := req.URL.Query()
+			if got := q.Get("startDate"); got != tt.wantStart {
+				t.Errorf("startDate = %q, want %q", got, tt.wantStart)
+			}
+			if got := q.Get("endDate"); got != tt.wantEnd {
+				t.Errorf("endDate = %q, want %q", got, tt.wantEnd)
+			}
+			if _, ok := q["startDate"]; ok != (tt.wantStart != "") {
+				t.Errorf("startDate present = %v, want %v", ok, tt.wantStart != "")
+			}
+			if _, ok := q["endDate"]; ok != (tt.wantEnd != "") {
+				t.Errorf("endDate present = %v, want %v", ok, tt.wantEnd != "")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package kis
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy defines how failed requests are retried. Requests are retried on
+// 429, 502, 503 and 504 responses as well as on network errors.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one. A value of 1 or less disables retries.
+	MaxAttempts int
+	// InitialBackoff is the base delay before the first retry, it is doubled for every further attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed delay between two attempts. Zero means no limit.
+	MaxBackoff time.Duration
+	// MaxRetryAfter caps the delay requested by a Retry-After header that is waited for. If the
+	// server asks for a longer delay, the request is not retried and the error is returned with
+	// APIError.RetryAfter set. Zero means no limit.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy is used if no other policy is set with WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	MaxRetryAfter:  2 * time.Minute,
+}
+
+// NoRetry disables retries.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy sets the retry policy used for all requests including the token request.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(cfg *config) {
+		cfg.retry = p
+	}
+}
+
+// backoff returns the jittered delay before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// equal jitter: half of the delay is fixed, the other half random
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryableStatus reports whether a response with the given status code should be retried.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
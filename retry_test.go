@@ -0,0 +1,103 @@
+package kis
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// retryAfterServer answers the first request with 429 and the given Retry-After header and all
+// further ones with 200.
+func retryAfterServer(t *testing.T, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func doGet(t *testing.T, e *executor, url string) *http.Response {
+	resp, err := e.do(context.Background(), RequestInfo{Endpoint: "position"}, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, url, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestRetryAfterHonored(t *testing.T) {
+	srv, requests := retryAfterServer(t, "1")
+	// The Retry-After delay is longer than MaxBackoff but within MaxRetryAfter.
+	e := newConfig([]Option{WithRetryPolicy(RetryPolicy{MaxAttempts: 2, MaxBackoff: 10 * time.Millisecond, MaxRetryAfter: 5 * time.Second})}).executor()
+	start := time.Now()
+	resp := doGet(t, e, srv.URL)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Errorf("retried after %v, want at least 1s", d)
+	}
+}
+
+func TestRetryAfterDefaultPolicy(t *testing.T) {
+	if DefaultRetryPolicy.MaxRetryAfter < time.Minute {
+		t.Errorf("DefaultRetryPolicy.MaxRetryAfter = %v, want a Retry-After of 60s to be honored", DefaultRetryPolicy.MaxRetryAfter)
+	}
+}
+
+func TestRetryAfterAboveLimit(t *testing.T) {
+	srv, requests := retryAfterServer(t, "60")
+	e := newConfig([]Option{WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MaxRetryAfter: time.Second})}).executor()
+	start := time.Now()
+	resp := doGet(t, e, srv.URL)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", resp.StatusCode)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("returned after %v, want no wait", d)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		retry  int
+		// the jittered delay is between half of base and base
+		base time.Duration
+	}{
+		{"first retry", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute}, 1, time.Second},
+		{"doubled", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute}, 3, 4 * time.Second},
+		{"capped", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 4, 5 * time.Second},
+		{"no cap", RetryPolicy{InitialBackoff: time.Second}, 5, 16 * time.Second},
+		{"no cap overflow", RetryPolicy{InitialBackoff: time.Second}, 100, math.MaxInt64},
+		{"no initial backoff", RetryPolicy{MaxBackoff: time.Second}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if d := tt.policy.backoff(tt.retry); d < tt.base/2 || d > tt.base {
+					t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.retry, d, tt.base/2, tt.base)
+				}
+			}
+		})
+	}
+}
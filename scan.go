@@ -0,0 +1,238 @@
+package kis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Scanner streams the items of a historical query. The date range is requested window by window and the
+// Payload array of each response is decoded item by item, so memory use does not grow with the range.
+//
+//	s := k.ScanHistoricalPositionByMachineUUID(ctx, mId, "", start, end, kis.ScanOptions{})
+//	defer s.Close()
+//	for s.Next() {
+//		p := s.Item()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// Items on the boundary of two windows are returned only once. The windows are requested sequentially.
+// A Scanner is not safe for concurrent use.
+type Scanner[T any] struct {
+	k       *Kubota
+	ctx     context.Context
+	call    CallInfo
+	value   string
+	key     func(T) string
+	stamp   func(T) time.Time
+	windows []window
+	next    int
+
+	resp  *response
+	dec   *json.Decoder
+	items int
+	cur   window
+	meta  ResponseMeta
+
+	// seen holds the keys of the items on the end of the previous window, edge those on the end of the current one.
+	seen, edge map[string]bool
+
+	item T
+	err  error
+}
+
+// ScanOptions configures how a Scanner splits a date range.
+type ScanOptions struct {
+	// Window is the length of a single request window, DefaultChunkWindow if zero.
+	Window time.Duration
+}
+
+// ScanHistoricalPositionByMachineUUID returns a Scanner streaming the historical positions of a machine.
+func (k *Kubota) ScanHistoricalPositionByMachineUUID(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time, opts ScanOptions) *Scanner[Position] {
+	call := CallInfo{Operation: "GetHistoricalPosition", Endpoint: "position", Field: "machineUUID", Subscription: subscription}
+	return newScanner(ctx, k, call, machineUUID, startDate, endDate, opts, positionKey, func(p Position) time.Time { return p.Timestamp.Time })
+}
+
+// ScanHistoricalMeasureByMachineUUID returns a Scanner streaming the historical measures of a machine.
+func (k *Kubota) ScanHistoricalMeasureByMachineUUID(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time, opts ScanOptions) *Scanner[Measure] {
+	call := CallInfo{Operation: "GetHistoricalMeasure", Endpoint: "measure", Field: "machineUUID", Subscription: subscription}
+	return newScanner(ctx, k, call, machineUUID, startDate, endDate, opts, measureKey, func(m Measure) time.Time { return m.Timestamp.Time })
+}
+
+// ScanHistoricalAlarmByMachineUUID returns a Scanner streaming the historical alarms of a machine.
+func (k *Kubota) ScanHistoricalAlarmByMachineUUID(ctx context.Context, machineUUID, subscription string, startDate, endDate time.Time, opts ScanOptions) *Scanner[Alarm] {
+	call := CallInfo{Operation: "GetHistoricalAlarm", Endpoint: "alarm", Field: "machineUUID", Subscription: subscription}
+	return newScanner(ctx, k, call, machineUUID, startDate, endDate, opts, alarmKey, func(a Alarm) time.Time { return a.Timestamp.Time })
+}
+
+func newScanner[T any](ctx context.Context, k *Kubota, call CallInfo, value string, startDate, endDate time.Time, opts ScanOptions, key func(T) string, stamp func(T) time.Time) *Scanner[T] {
+	s := &Scanner[T]{k: k, ctx: ctx, call: call, value: value, key: key, stamp: stamp}
+	if startDate.IsZero() || endDate.IsZero() || !startDate.Before(endDate) {
+		s.err = errors.New("error splitting date range: start date must be before end date")
+		return s
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultChunkWindow
+	}
+	s.windows = splitRange(startDate, endDate, opts.Window)
+	return s
+}
+
+// Next advances to the next item, which is then available through Item. It returns false when
+// all windows are exhausted or an error occurred, which is then returned by Err.
+func (s *Scanner[T]) Next() bool {
+	for s.err == nil {
+		if s.resp == nil {
+			if s.next == len(s.windows) {
+				return false
+			}
+			s.next++
+			s.err = s.open(s.windows[s.next-1])
+			continue
+		}
+		if !s.dec.More() {
+			s.err = s.closeWindow()
+			continue
+		}
+		var item T
+		if err := s.dec.Decode(&item); err != nil {
+			s.fail(fmt.Errorf("error decoding %s response: %w", s.call.Endpoint, err))
+			return false
+		}
+		s.items++
+		k := s.key(item)
+		if s.stamp(item).Equal(s.cur.end) {
+			s.edge[k] = true
+		}
+		if s.seen[k] {
+			continue
+		}
+		s.item = item
+		return true
+	}
+	return false
+}
+
+// Item returns the current item.
+func (s *Scanner[T]) Item() T {
+	return s.item
+}
+
+// Meta returns the envelope metadata of the response of the window Item belongs to. Only the fields
+// preceding the Payload in the response are set.
+func (s *Scanner[T]) Meta() ResponseMeta {
+	return s.meta
+}
+
+// Err returns the error that stopped the scanner, if any. A failed window is wrapped in a *WindowError.
+func (s *Scanner[T]) Err() error {
+	return s.err
+}
+
+// errScannerClosed ends the span of a response abandoned by Scanner.Close.
+var errScannerClosed = errors.New("scanner closed before end of payload")
+
+// Close releases the response of the current window and stops the scanner. It is safe to call Close
+// more than once.
+func (s *Scanner[T]) Close() error {
+	if s.resp != nil {
+		s.resp.finish(s.items, errScannerClosed)
+		s.resp = nil
+	}
+	s.next = len(s.windows)
+	return nil
+}
+
+// open requests the window and advances the decoder to the first element of the Payload array.
+func (s *Scanner[T]) open(w window) error {
+	call := s.call
+	call.StartDate, call.EndDate = w.start, w.end
+	r, err := s.k.begin(s.ctx, call, s.value)
+	if err != nil {
+		return &WindowError{StartDate: w.start, EndDate: w.end, Err: err}
+	}
+	s.resp, s.dec, s.items, s.cur = r, json.NewDecoder(r.body), 0, w
+	s.seen, s.edge = s.edge, make(map[string]bool)
+	if err := s.payload(); err != nil {
+		s.fail(fmt.Errorf("error decoding %s response: %w", call.Endpoint, err))
+	}
+	return s.err
+}
+
+// payload reads the envelope up to the opening bracket of the Payload array. The metadata fields
+// are recorded, unknown fields are skipped. A missing or null Payload leaves the decoder at the end
+// of the envelope.
+func (s *Scanner[T]) payload() error {
+	if err := expectDelim(s.dec, '{'); err != nil {
+		return err
+	}
+	var meta ResponseMeta
+	for s.dec.More() {
+		t, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case "Payload":
+			t, err := s.dec.Token()
+			if err != nil {
+				return err
+			}
+			if t == nil {
+				continue
+			}
+			if t != json.Delim('[') {
+				return fmt.Errorf("unexpected %v at start of payload", t)
+			}
+			s.meta = meta
+			return nil
+		case "Status":
+			err = s.dec.Decode(&meta.Status)
+		case "Resource":
+			err = s.dec.Decode(&meta.Resource)
+		default:
+			var skip json.RawMessage
+			err = s.dec.Decode(&skip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	s.meta = meta
+	return nil
+}
+
+// closeWindow consumes the closing bracket of the Payload array and finishes the response.
+// The remainder of the envelope is not read.
+func (s *Scanner[T]) closeWindow() error {
+	r := s.resp
+	s.resp = nil
+	if _, err := s.dec.Token(); err != nil {
+		err = fmt.Errorf("error decoding %s response: %w", s.call.Endpoint, err)
+		return &WindowError{StartDate: s.cur.start, EndDate: s.cur.end, Err: r.finish(s.items, err)}
+	}
+	return r.finish(s.items, nil)
+}
+
+// fail finishes the current response with err and stops the scanner.
+func (s *Scanner[T]) fail(err error) {
+	s.err = &WindowError{StartDate: s.cur.start, EndDate: s.cur.end, Err: s.resp.finish(s.items, err)}
+	s.resp = nil
+}
+
+// expectDelim reads the next token and checks that it is the delimiter d.
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != d {
+		return fmt.Errorf("unexpected %v, expected %v", t, d)
+	}
+	return nil
+}
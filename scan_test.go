@@ -0,0 +1,195 @@
+package kis_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"github.com/maltegrosse/go-kubota-kis-api/kisfake"
+	"github.com/maltegrosse/go-kubota-kis-api/kistest"
+)
+
+var scanStart = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// rawClient returns a client whose first position request is answered with body and a server
+// answering all further ones with an empty Payload.
+func rawClient(t *testing.T, body string) (*kis.Kubota, *kistest.Server) {
+	t.Helper()
+	srv := kistest.Start(t, nil)
+	if body != "" {
+		srv.Fail("position", kistest.Failure{Status: http.StatusOK, Body: body})
+	}
+	return srv.NewClient(t, kis.WithRetryPolicy(kis.NoRetry)), srv
+}
+
+// scanAll scans a single day in one window and returns the timestamps of the items.
+func scanAll(t *testing.T, k *kis.Kubota) ([]string, error) {
+	t.Helper()
+	s := k.ScanHistoricalPositionByMachineUUID(context.Background(), "m1", "", scanStart, scanStart.Add(24*time.Hour), kis.ScanOptions{Window: 24 * time.Hour})
+	defer s.Close()
+	var stamps []string
+	for s.Next() {
+		stamps = append(stamps, s.Item().Timestamp.Format("15:04"))
+	}
+	return stamps, s.Err()
+}
+
+const twoItems = `[{"MachineUUID":"m1","Timestamp":"2024-03-01T01:00:00"},{"MachineUUID":"m1","Timestamp":"2024-03-01T02:00:00"}]`
+
+func TestScannerEnvelope(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"payload last", `{"Status":200,"Resource":"position","Payload":` + twoItems + `}`, []string{"01:00", "02:00"}},
+		{"payload first", `{"Payload":` + twoItems + `,"Status":200,"Resource":"position"}`, []string{"01:00", "02:00"}},
+		{"payload between", `{"Status":200,"Payload":` + twoItems + `,"Resource":"position"}`, []string{"01:00", "02:00"}},
+		{"unknown fields skipped", `{"Extra":{"Nested":[1,{"Payload":[]}]},"Status":200,"Payload":` + twoItems + `}`, []string{"01:00", "02:00"}},
+		{"empty payload", `{"Status":200,"Resource":"position","Payload":[]}`, nil},
+		{"null payload", `{"Status":200,"Resource":"position","Payload":null}`, nil},
+		{"missing payload", `{"Status":200,"Resource":"position"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, _ := rawClient(t, tt.body)
+			got, err := scanAll(t, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScannerDecodeError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"invalid item", `{"Payload":[{"MachineUUID":"m1","Timestamp":"2024-03-01T01:00:00"},{"MachineUUID":5}]}`, []string{"01:00"}},
+		{"invalid timestamp", `{"Payload":[{"MachineUUID":"m1","Timestamp":"2024-03-01T01:00:00"},{"MachineUUID":"m1","Timestamp":"noon"}]}`, []string{"01:00"}},
+		{"truncated array", `{"Payload":[{"MachineUUID":"m1","Timestamp":"2024-03-01T01:00:00"},`, []string{"01:00"}},
+		{"payload not an array", `{"Payload":{"MachineUUID":"m1"}}`, nil},
+		{"not an object", `[]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, _ := rawClient(t, tt.body)
+			got, err := scanAll(t, k)
+			var windowErr *kis.WindowError
+			if !errors.As(err, &windowErr) {
+				t.Fatalf("error = %v, want *kis.WindowError", err)
+			}
+			if !windowErr.StartDate.Equal(scanStart) || !strings.Contains(err.Error(), "error decoding position response") {
+				t.Errorf("error = %v, want a decoding error of the window starting at %v", err, scanStart)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScannerCloseMidWindow(t *testing.T) {
+	k, srv := rawClient(t, `{"Payload":`+twoItems+`}`)
+	s := k.ScanHistoricalPositionByMachineUUID(context.Background(), "m1", "", scanStart, scanStart.Add(72*time.Hour), kis.ScanOptions{Window: 24 * time.Hour})
+	if !s.Next() {
+		t.Fatalf("Next() = false, error %v", s.Err())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Next() {
+		t.Error("Next() after Close = true")
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Err() after Close = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	// the remaining windows are not requested
+	if n := srv.Requests("position"); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestScannerInvalidRange(t *testing.T) {
+	k, srv := rawClient(t, "")
+	s := k.ScanHistoricalPositionByMachineUUID(context.Background(), "m1", "", scanStart, scanStart, kis.ScanOptions{})
+	if s.Next() {
+		t.Error("Next() = true")
+	}
+	if s.Err() == nil {
+		t.Error("Err() = nil, want an error for an empty range")
+	}
+	if n := srv.Requests("position"); n != 0 {
+		t.Errorf("requests = %d, want 0", n)
+	}
+}
+
+func newScanServer(t *testing.T) (*kistest.Server, *kis.Kubota) {
+	t.Helper()
+	// hourly positions over three days, including the boundaries of the daily windows
+	var positions []kis.Position
+	for h := 0; h < 72; h++ {
+		positions = append(positions, kis.Position{MachineUUID: "m1", Timestamp: kis.CustomTime{Time: scanStart.Add(time.Duration(h) * time.Hour)}})
+	}
+	srv := kistest.Start(t, &kisfake.Client{Positions: positions})
+	return srv, srv.NewClient(t, kis.WithRetryPolicy(kis.NoRetry))
+}
+
+func TestScannerWindowBoundaries(t *testing.T) {
+	srv, k := newScanServer(t)
+	s := k.ScanHistoricalPositionByMachineUUID(context.Background(), "m1", "", scanStart, scanStart.Add(71*time.Hour), kis.ScanOptions{Window: 24 * time.Hour})
+	defer s.Close()
+	var got []time.Time
+	for s.Next() {
+		got = append(got, s.Item().Timestamp.Time)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 72 {
+		t.Fatalf("got %d positions, want 72", len(got))
+	}
+	for i, ts := range got {
+		if want := scanStart.Add(time.Duration(i) * time.Hour); !ts.Equal(want) {
+			t.Fatalf("position %d at %v, want %v", i, ts, want)
+		}
+	}
+	if n := srv.Requests("position"); n != 3 {
+		t.Errorf("position requests = %d, want 3", n)
+	}
+}
+
+func TestScannerWindowError(t *testing.T) {
+	srv, k := newScanServer(t)
+	s := k.ScanHistoricalPositionByMachineUUID(context.Background(), "m1", "", scanStart, scanStart.Add(71*time.Hour), kis.ScanOptions{Window: 24 * time.Hour})
+	defer s.Close()
+	n := 0
+	for s.Next() {
+		// the first window is already open, so the failure hits the second one
+		if n++; n == 1 {
+			srv.Fail("position", kistest.Failure{Status: 500})
+		}
+	}
+	var windowErr *kis.WindowError
+	if !errors.As(s.Err(), &windowErr) || !errors.Is(s.Err(), kis.ErrServer) {
+		t.Fatalf("error = %v, want *kis.WindowError wrapping ErrServer", s.Err())
+	}
+	if want := scanStart.Add(24 * time.Hour); !windowErr.StartDate.Equal(want) {
+		t.Errorf("failed window starts at %v, want %v", windowErr.StartDate, want)
+	}
+	if n != 25 {
+		t.Errorf("got %d positions before the error, want 25", n)
+	}
+}
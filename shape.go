@@ -0,0 +1,99 @@
+package kis
+
+import "math"
+
+// Contains reports whether the point given in degrees lies within the shape. The coordinates of the
+// shape are GeoJSON [longitude, latitude] pairs. The rings are combined with the even-odd rule, so
+// inner rings are holes of the outer ring, and rings which do not overlap each other are separate
+// parts of the field. Points on the boundary may be reported either way.
+func (s Shape) Contains(latitude, longitude float64) bool {
+	inside := false
+	for _, ring := range s.Coordinates {
+		if ringContains(ring, latitude, longitude) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ringContains casts a ray from the point towards increasing longitude and counts the edges of the
+// ring it crosses. The ring does not need to be closed.
+func ringContains(ring [][2]float64, latitude, longitude float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > latitude) != (b[1] > latitude) &&
+			longitude < (b[0]-a[0])*(latitude-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// BoundingBox is the smallest box in degrees containing a shape.
+type BoundingBox struct {
+	MinLatitude, MinLongitude, MaxLatitude, MaxLongitude float64
+}
+
+// BoundingBox returns the bounding box of the shape. The box of a shape without coordinates is empty
+// and contains no point.
+func (s Shape) BoundingBox() BoundingBox {
+	b := BoundingBox{MinLatitude: math.Inf(1), MinLongitude: math.Inf(1), MaxLatitude: math.Inf(-1), MaxLongitude: math.Inf(-1)}
+	for _, ring := range s.Coordinates {
+		for _, c := range ring {
+			b.MinLongitude, b.MaxLongitude = math.Min(b.MinLongitude, c[0]), math.Max(b.MaxLongitude, c[0])
+			b.MinLatitude, b.MaxLatitude = math.Min(b.MinLatitude, c[1]), math.Max(b.MaxLatitude, c[1])
+		}
+	}
+	return b
+}
+
+// Contains reports whether the point given in degrees lies within the box, including its edges.
+func (b BoundingBox) Contains(latitude, longitude float64) bool {
+	return latitude >= b.MinLatitude && latitude <= b.MaxLatitude && longitude >= b.MinLongitude && longitude <= b.MaxLongitude
+}
+
+// FieldIndex finds the field a point lies in. It is safe for concurrent use.
+type FieldIndex struct {
+	fields []Field
+	bounds []BoundingBox
+}
+
+// NewFieldIndex returns a FieldIndex of the fields. If fields overlap, the one given first wins.
+func NewFieldIndex(fields []Field) *FieldIndex {
+	ix := &FieldIndex{fields: fields, bounds: make([]BoundingBox, len(fields))}
+	for i, f := range fields {
+		ix.bounds[i] = f.Shape.BoundingBox()
+	}
+	return ix
+}
+
+// Lookup returns the field the point given in degrees lies in, or false if it lies in none.
+func (ix *FieldIndex) Lookup(latitude, longitude float64) (*Field, bool) {
+	for i := range ix.fields {
+		if ix.bounds[i].Contains(latitude, longitude) && ix.fields[i].Shape.Contains(latitude, longitude) {
+			return &ix.fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// FieldPosition is a position tagged with the field it lies in.
+type FieldPosition struct {
+	Position
+	// FieldID is the ID of the field, or empty if the position lies in none.
+	FieldID string
+}
+
+// MatchFields tags every position with the field it lies in. If fields overlap, the one given first wins.
+func MatchFields(positions []Position, fields []Field) []FieldPosition {
+	ix := NewFieldIndex(fields)
+	tagged := make([]FieldPosition, len(positions))
+	for i, p := range positions {
+		tagged[i].Position = p
+		if f, ok := ix.Lookup(p.Latitude, p.Longitude); ok {
+			tagged[i].FieldID = f.FieldID
+		}
+	}
+	return tagged
+}
@@ -0,0 +1,104 @@
+package kis
+
+import "testing"
+
+func TestShapeContains(t *testing.T) {
+	// a field from 10° to 10.01° with a hole from 10.004° to 10.006°, and a second part from 10.02° to 10.03°
+	s := Shape{Type: "Polygon", Coordinates: [][][2]float64{
+		square(10, 10, 0.01),
+		reversed(square(10.004, 10.004, 0.002)),
+		square(10.02, 10, 0.01),
+	}}
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		want      bool
+	}{
+		{"inside", 10.002, 10.002, true},
+		{"in the hole", 10.005, 10.005, false},
+		{"between hole and outer ring", 10.005, 10.008, true},
+		{"in the second part", 10.005, 10.025, true},
+		{"between the parts, inside the bounding box", 10.005, 10.015, false},
+		{"north of the bounding box", 10.02, 10.005, false},
+		{"west of the bounding box", 10.005, 9.99, false},
+		{"east of the bounding box", 10.005, 10.04, false},
+		{"latitude and longitude swapped", 10.025, 10.005, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Contains(tt.latitude, tt.longitude); got != tt.want {
+				t.Errorf("Contains(%v, %v) = %v, want %v", tt.latitude, tt.longitude, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShapeContainsOpenRing(t *testing.T) {
+	ring := square(0, 0, 1)
+	s := Shape{Coordinates: [][][2]float64{ring[:len(ring)-1]}}
+	if !s.Contains(0.5, 0.5) {
+		t.Error("Contains(0.5, 0.5) = false for a ring that is not closed")
+	}
+	if (Shape{}).Contains(0, 0) {
+		t.Error("empty shape contains 0, 0")
+	}
+}
+
+func TestMatchFields(t *testing.T) {
+	fields := []Field{
+		{FieldID: "north", Shape: Shape{Coordinates: [][][2]float64{square(10, 10.01, 0.01)}}},
+		// overlaps the northern half of north
+		{FieldID: "overlap", Shape: Shape{Coordinates: [][][2]float64{square(10, 10.015, 0.01)}}},
+		{FieldID: "holed", Shape: Shape{Coordinates: [][][2]float64{square(10, 10, 0.01), reversed(square(10.004, 10.004, 0.002))}}},
+		{FieldID: "parts", Shape: Shape{Coordinates: [][][2]float64{square(10.02, 10, 0.01), square(10.04, 10, 0.01)}}},
+	}
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		want      string
+	}{
+		{"first field", 10.012, 10.005, "north"},
+		{"overlapping fields", 10.018, 10.005, "north"},
+		{"second field only", 10.022, 10.005, "overlap"},
+		{"outside the hole", 10.002, 10.002, "holed"},
+		{"in a hole", 10.005, 10.005, ""},
+		{"first part", 10.005, 10.025, "parts"},
+		{"second part", 10.005, 10.045, "parts"},
+		{"between the parts", 10.005, 10.035, ""},
+		{"outside all bounding boxes", 20, 20, ""},
+	}
+	positions := make([]Position, len(tests))
+	for i, tt := range tests {
+		positions[i] = Position{MachineUUID: tt.name, Latitude: tt.latitude, Longitude: tt.longitude}
+	}
+	tagged := MatchFields(positions, fields)
+	if len(tagged) != len(positions) {
+		t.Fatalf("got %d positions, want %d", len(tagged), len(positions))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tagged[i].MachineUUID != tt.name {
+				t.Errorf("position %d is %q, want the input order", i, tagged[i].MachineUUID)
+			}
+			if tagged[i].FieldID != tt.want {
+				t.Errorf("FieldID = %q, want %q", tagged[i].FieldID, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldIndexLookup(t *testing.T) {
+	ix := NewFieldIndex([]Field{{FieldID: "a", Shape: Shape{Coordinates: [][][2]float64{square(0, 0, 1)}}}})
+	f, ok := ix.Lookup(0.5, 0.5)
+	if !ok || f.FieldID != "a" {
+		t.Errorf("Lookup(0.5, 0.5) = %v, %v, want field a", f, ok)
+	}
+	if f, ok := ix.Lookup(1.5, 0.5); ok {
+		t.Errorf("Lookup(1.5, 0.5) = %v, want none", f)
+	}
+	if _, ok := NewFieldIndex(nil).Lookup(0, 0); ok {
+		t.Error("Lookup() of an empty index found a field")
+	}
+}
@@ -1,37 +1,78 @@
 package kis
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 )
 
 // CustomTime is a custom time type that allows for JSON unmarshalling.
+// The time is always held in UTC.
 type CustomTime struct {
 	time.Time
 }
 
-// dateLayout is the layout for the date format. UTC datetime in ISO 8601 format (YYYY-MM-DDThh:mm:ssZ).
+// dateLayout is the layout for the date format. UTC datetime in ISO 8601 format (YYYY-MM-DDThh:mm:ss),
+// as expected by the API in query parameters.
 const dateLayout = "2006-01-02T15:04:05"
 
+// marshalLayout has optional fractional seconds, so marshalling does not lose precision, and the Z
+// designator, so other consumers of the JSON do not take the time for local time.
+const marshalLayout = time.RFC3339Nano
+
+// parseLayouts are the ISO 8601 variants accepted when parsing timestamps. Timestamps
+// without a zone offset are interpreted as UTC.
+var parseLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999Z07",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTime parses an ISO 8601 timestamp and converts it to UTC.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range parseLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("error parsing time: %q is not an ISO 8601 timestamp", s)
+}
+
+// formatTime formats t in UTC with the layout expected by the API.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateLayout)
+}
+
 // UnmarshalJSON unmarshals a JSON string into a CustomTime type.
 func (ct *CustomTime) UnmarshalJSON(b []byte) (err error) {
-	s := strings.Trim(string(b), "\"")
-	if s == "null" || len(s) == 0 {
+	if string(b) == "null" {
 		ct.Time = time.Time{}
-		return
+		return nil
 	}
-	ct.Time, err = time.Parse(dateLayout, s)
-	if err != nil {
-		panic(fmt.Errorf("error parsing time: %w", err))
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("error parsing time: %w", err)
+	}
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		ct.Time = time.Time{}
+		return nil
 	}
-	return
+	ct.Time, err = parseTime(s)
+	return err
 }
 
-// MarshalJSON marshals a CustomTime type into a JSON string.
-func (ct *CustomTime) MarshalJSON() ([]byte, error) {
+// MarshalJSON marshals a CustomTime type into a JSON string in UTC, e.g. "2024-03-01T12:00:00Z".
+func (ct CustomTime) MarshalJSON() ([]byte, error) {
 	if ct.Time.IsZero() {
 		return []byte("null"), nil
 	}
-	return []byte(ct.Time.Format(dateLayout)), nil
+	return []byte(`"` + ct.Time.UTC().Format(marshalLayout) + `"`), nil
 }
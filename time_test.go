@@ -0,0 +1,137 @@
+package kis
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+var parseTests = []struct {
+	in   string
+	want time.Time
+}{
+	{"2024-03-01T12:30:45Z", time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)},
+	{"2024-03-01T12:30:45.123456789+02:00", time.Date(2024, 3, 1, 10, 30, 45, 123456789, time.UTC)},
+	{"2024-03-01T12:30:45", time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)},
+	{"2024-03-01T12:30:45.5", time.Date(2024, 3, 1, 12, 30, 45, 5e8, time.UTC)},
+	{"2024-03-01T12:30:45+0200", time.Date(2024, 3, 1, 10, 30, 45, 0, time.UTC)},
+	{"2024-03-01T12:30:45.25-0130", time.Date(2024, 3, 1, 14, 0, 45, 25e7, time.UTC)},
+	{"2024-03-01T12:30:45-05", time.Date(2024, 3, 1, 17, 30, 45, 0, time.UTC)},
+	{"2024-03-01T12:30+01:00", time.Date(2024, 3, 1, 11, 30, 0, 0, time.UTC)},
+	{"2024-03-01T12:30Z", time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)},
+	{"2024-03-01T12:30", time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)},
+	{"2024-03-01 12:30:45+02:00", time.Date(2024, 3, 1, 10, 30, 45, 0, time.UTC)},
+	{"2024-03-01 12:30:45", time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)},
+	{"2024-03-01 12:30:45.25", time.Date(2024, 3, 1, 12, 30, 45, 25e7, time.UTC)},
+	{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	{"2024-02-29T23:30:00-01:00", time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)},
+}
+
+func TestParseTime(t *testing.T) {
+	for _, tt := range parseTests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseTime(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("parseTime(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLayoutsCovered(t *testing.T) {
+	for _, layout := range parseLayouts {
+		covered := false
+		for _, tt := range parseTests {
+			if _, err := time.Parse(layout, tt.in); err == nil {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			t.Errorf("no test input for layout %q", layout)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"string", `"2024-03-01T12:30:45+02:00"`, time.Date(2024, 3, 1, 10, 30, 45, 0, time.UTC), false},
+		{"null", `null`, time.Time{}, false},
+		{"empty string", `""`, time.Time{}, false},
+		{"blank string", `"  "`, time.Time{}, false},
+		{"surrounding space", `" 2024-03-01T12:30:45 "`, time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC), false},
+		{"number", `1709296245`, time.Time{}, true},
+		{"boolean", `true`, time.Time{}, true},
+		{"object", `{}`, time.Time{}, true},
+		{"array", `["2024-03-01"]`, time.Time{}, true},
+		{"not a timestamp", `"noon"`, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a previous value is replaced
+			ct := CustomTime{Time: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+			err := json.Unmarshal([]byte(tt.in), &ct)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !ct.Time.Equal(tt.want) {
+				t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, ct.Time, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	cest := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+	}{
+		{"UTC", time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC), `"2024-03-01T12:30:45Z"`},
+		{"converted to UTC", time.Date(2024, 6, 1, 8, 30, 0, 0, cest), `"2024-06-01T06:30:00Z"`},
+		{"fractional seconds", time.Date(2024, 3, 1, 12, 30, 45, 120000000, time.UTC), `"2024-03-01T12:30:45.12Z"`},
+		{"zero", time.Time{}, `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(CustomTime{Time: tt.in})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", b, tt.want)
+			}
+			var back CustomTime
+			if err := json.Unmarshal(b, &back); err != nil {
+				t.Fatal(err)
+			}
+			if !back.Time.Equal(tt.in) {
+				t.Errorf("round trip = %v, want %v", back.Time, tt.in)
+			}
+		})
+	}
+}
+
+func TestMarshalJSONField(t *testing.T) {
+	p := Position{MachineUUID: "m1", Timestamp: CustomTime{Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}}
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Position
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !back.Timestamp.Equal(p.Timestamp.Time) || !back.CreateTime.IsZero() {
+		t.Errorf("round trip of %s = %+v, want %+v", b, back, p)
+	}
+}
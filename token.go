@@ -0,0 +1,156 @@
+package kis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Token represents an access token of the Kubota API.
+type Token struct {
+	AccessToken string    `json:"AccessToken"`
+	TokenType   string    `json:"TokenType"`
+	Expiry      time.Time `json:"Expiry"`
+}
+
+// Valid reports whether the token is set and does not expire within the next minute.
+// A token with a zero Expiry never expires.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Before(t.Expiry.Add(-tokenExpiryMargin))
+}
+
+// TokenSource provides access tokens for the Kubota API. The client caches the
+// returned token and only asks the source again shortly before it expires.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// tokenInvalidator is implemented by token sources which cache tokens themselves,
+// so that a token rejected by the API is not handed out again.
+type tokenInvalidator interface {
+	invalidate(accessToken string)
+}
+
+// WithTokenSource replaces the default public/secret key flow with the given token source.
+func WithTokenSource(ts TokenSource) Option {
+	return func(cfg *config) {
+		cfg.tokenSource = ts
+	}
+}
+
+// WithTokenCacheFile caches the token in the file at path, so an unexpired token is
+// reused across runs of the program. It wraps the token source of the client.
+func WithTokenCacheFile(path string) Option {
+	return func(cfg *config) {
+		cfg.tokenCacheFile = path
+	}
+}
+
+// staticTokenSource always returns the same token.
+type staticTokenSource struct {
+	token *Token
+}
+
+// StaticTokenSource returns a TokenSource which always returns the given token.
+func StaticTokenSource(t *Token) TokenSource {
+	return &staticTokenSource{token: t}
+}
+
+// Token implements the TokenSource interface.
+func (s *staticTokenSource) Token(ctx context.Context) (*Token, error) {
+	if s.token == nil || s.token.AccessToken == "" {
+		return nil, errors.New("error with static token: token is empty")
+	}
+	t := *s.token
+	return &t, nil
+}
+
+// fileTokenSource caches the tokens of another source in a file.
+type fileTokenSource struct {
+	path string
+	src  TokenSource
+	mu   sync.Mutex
+}
+
+// FileTokenSource returns a TokenSource which reuses the unexpired token stored in the
+// file at path and otherwise asks src for a new token and writes it to the file.
+// The file is created with permissions 0600.
+func FileTokenSource(path string, src TokenSource) TokenSource {
+	return &fileTokenSource{path: path, src: src}
+}
+
+// Token implements the TokenSource interface.
+func (f *fileTokenSource) Token(ctx context.Context) (*Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// an unreadable or corrupt cache file is treated like a missing one
+	if t, err := f.read(); err == nil && t.Valid() {
+		return t, nil
+	}
+	t, err := f.src.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.write(t); err != nil {
+		return nil, fmt.Errorf("error writing token cache: %w", err)
+	}
+	return t, nil
+}
+
+// invalidate removes the cached token if it is the given one.
+func (f *fileTokenSource) invalidate(accessToken string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t, err := f.read(); err == nil && t.AccessToken == accessToken {
+		_ = os.Remove(f.path)
+	}
+	if inv, ok := f.src.(tokenInvalidator); ok {
+		inv.invalidate(accessToken)
+	}
+}
+
+// read loads the token from the cache file.
+func (f *fileTokenSource) read() (*Token, error) {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	var t Token
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// write stores the token in the cache file, replacing it atomically.
+func (f *fileTokenSource) write(t *Token) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
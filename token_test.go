@@ -0,0 +1,175 @@
+package kis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// sequenceSource returns the tokens "t1", "t2", ... valid for an hour and records invalidations.
+type sequenceSource struct {
+	calls       int
+	err         error
+	invalidated []string
+}
+
+func (s *sequenceSource) Token(ctx context.Context) (*Token, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.calls++
+	return &Token{AccessToken: "t" + strconv.Itoa(s.calls), TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func (s *sequenceSource) invalidate(accessToken string) {
+	s.invalidated = append(s.invalidated, accessToken)
+}
+
+func writeTokenFile(t *testing.T, path string, tok Token) {
+	t.Helper()
+	b, err := json.Marshal(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTokenFile(t *testing.T, path string) Token {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tok Token
+	if err := json.Unmarshal(b, &tok); err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestFileTokenSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      func(t *testing.T, path string)
+		wantToken string
+		wantCalls int
+	}{
+		{"missing file", func(*testing.T, string) {}, "t1", 1},
+		{"unexpired token reused", func(t *testing.T, path string) {
+			writeTokenFile(t, path, Token{AccessToken: "cached", Expiry: time.Now().Add(time.Hour)})
+		}, "cached", 0},
+		{"token without expiry reused", func(t *testing.T, path string) {
+			writeTokenFile(t, path, Token{AccessToken: "cached"})
+		}, "cached", 0},
+		{"expired token replaced", func(t *testing.T, path string) {
+			writeTokenFile(t, path, Token{AccessToken: "cached", Expiry: time.Now().Add(-time.Minute)})
+		}, "t1", 1},
+		{"token expiring within the margin replaced", func(t *testing.T, path string) {
+			writeTokenFile(t, path, Token{AccessToken: "cached", Expiry: time.Now().Add(tokenExpiryMargin / 2)})
+		}, "t1", 1},
+		{"corrupt file", func(t *testing.T, path string) {
+			if err := os.WriteFile(path, []byte(`{"AccessToken":`), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}, "t1", 1},
+		{"empty token", func(t *testing.T, path string) {
+			writeTokenFile(t, path, Token{})
+		}, "t1", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token.json")
+			tt.file(t, path)
+			src := &sequenceSource{}
+			fs := FileTokenSource(path, src)
+			tok, err := fs.Token(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok.AccessToken != tt.wantToken || src.calls != tt.wantCalls {
+				t.Errorf("Token() = %q after %d calls of the source, want %q after %d", tok.AccessToken, src.calls, tt.wantToken, tt.wantCalls)
+			}
+			if cached := readTokenFile(t, path); cached.AccessToken != tt.wantToken {
+				t.Errorf("cached token = %q, want %q", cached.AccessToken, tt.wantToken)
+			}
+			// the second call is served from the file
+			if tok, err := fs.Token(context.Background()); err != nil || tok.AccessToken != tt.wantToken || src.calls != tt.wantCalls {
+				t.Errorf("second Token() = %v, %v after %d calls, want %q from the file", tok, err, src.calls, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestFileTokenSourcePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "token.json")
+	if _, err := FileTokenSource(path, &sequenceSource{}).Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode = %v, want 0600", mode)
+	}
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the cache file", len(entries))
+	}
+}
+
+func TestFileTokenSourceError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	errDown := errors.New("down")
+	if _, err := FileTokenSource(path, &sequenceSource{err: errDown}).Token(context.Background()); !errors.Is(err, errDown) {
+		t.Errorf("Token() error = %v, want the error of the source", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("cache file written after an error: %v", err)
+	}
+	if _, err := FileTokenSource(filepath.Join(path, "missing", "token.json"), &sequenceSource{}).Token(context.Background()); err == nil {
+		t.Error("Token() = nil error for a cache file in a missing directory")
+	}
+}
+
+func TestFileTokenSourceInvalidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	src := &sequenceSource{}
+	fs := FileTokenSource(path, src).(*fileTokenSource)
+	if _, err := fs.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// another token, e.g. refreshed by a concurrent process, is kept
+	fs.invalidate("other")
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("cache file removed for another token: %v", err)
+	}
+	fs.invalidate("t1")
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("cache file still present after invalidating its token: %v", err)
+	}
+	if want := []string{"other", "t1"}; !reflect.DeepEqual(src.invalidated, want) {
+		t.Errorf("invalidated in the source = %v, want %v", src.invalidated, want)
+	}
+	tok, err := fs.Token(context.Background())
+	if err != nil || tok.AccessToken != "t2" {
+		t.Errorf("Token() after invalidate = %v, %v, want a new token t2", tok, err)
+	}
+}
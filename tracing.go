@@ -0,0 +1,81 @@
+package kis
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// CallInfo describes a call of a getter of the client.
+type CallInfo struct {
+	// Operation is the name of the getter without the lookup field, e.g. "GetHistoricalPosition".
+	Operation string
+	// Endpoint is the name of the resource, e.g. "position".
+	Endpoint string
+	// Field is the lookup field, i.e. "machineUUID", "userName" or "mobilePhone".
+	Field        string
+	Subscription string
+	// StartDate and EndDate are the requested date range of historical getters, zero otherwise.
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// CallResult describes the outcome of a call of a getter.
+type CallResult struct {
+	// Status is the HTTP status code of the last response, 0 if there was none.
+	Status int
+	// Items is the number of items in the payload of the response.
+	Items int
+	// LogID is the LogId of an error response.
+	LogID string
+	// Duration is the total time of the call including retries and rate limit waits.
+	Duration time.Duration
+	Err      error
+}
+
+// Tracer starts a span for every call of a getter. The span context returned by Start is used
+// for the requests of the call including a token refresh it triggers.
+// The kisotel package provides an OpenTelemetry implementation.
+type Tracer interface {
+	Start(ctx context.Context, call CallInfo) (context.Context, Span)
+}
+
+// Span is the span of a single call started by a Tracer.
+type Span interface {
+	End(result CallResult)
+}
+
+// WithTracer sets the tracer for all calls of the client.
+func WithTracer(t Tracer) Option {
+	return func(cfg *config) {
+		cfg.tracer = t
+	}
+}
+
+// noopTracer is the default tracer which does nothing.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ CallInfo) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(CallResult) {}
+
+// payloadItems returns the number of items of a decoded payload: the length of a slice,
+// 0 for a nil pointer and 1 otherwise.
+func payloadItems(payload any) int {
+	v := reflect.ValueOf(payload)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.Slice:
+		return v.Len()
+	case reflect.Pointer:
+		if v.IsNil() {
+			return 0
+		}
+	}
+	return 1
+}
@@ -0,0 +1,241 @@
+package track
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+)
+
+// Step is the movement of a machine between two consecutive positions.
+type Step struct {
+	MachineUUID string
+	From        kis.Position
+	To          kis.Position
+	Duration    time.Duration
+	// Distance is the great-circle distance in meters.
+	Distance float64
+	// Speed is the speed in km/h reported with To, or derived from Distance and Duration if To has none.
+	Speed float64
+	// Derived is set if Speed was derived.
+	Derived bool
+}
+
+// Steps returns the steps between consecutive positions, grouped by MachineUUID in order of first
+// occurrence and sorted by Timestamp.
+func Steps(positions []kis.Position) []Step {
+	var steps []Step
+	for _, ps := range byMachine(positions) {
+		for i := 1; i < len(ps); i++ {
+			steps = append(steps, newStep(ps[i-1], ps[i]))
+		}
+	}
+	return steps
+}
+
+func newStep(a, b kis.Position) Step {
+	return Step{
+		MachineUUID: b.MachineUUID,
+		From:        a,
+		To:          b,
+		Duration:    b.Timestamp.Sub(a.Timestamp.Time),
+		Distance:    distance(a, b),
+		Speed:       speed(a, b),
+		Derived:     b.Speed == nil,
+	}
+}
+
+// Distance returns the total great-circle distance in meters traveled through the positions,
+// summed over all machines.
+func Distance(positions []kis.Position) float64 {
+	var d float64
+	for _, s := range Steps(positions) {
+		d += s.Distance
+	}
+	return d
+}
+
+// Summary holds the totals of a track.
+type Summary struct {
+	// Distance is the total distance in meters.
+	Distance float64
+	// MovingTime is the time spent at StationarySpeed or faster.
+	MovingTime time.Duration
+	// StationaryTime is the time spent below StationarySpeed.
+	StationaryTime time.Duration
+	// Points is the number of positions.
+	Points int
+	// Outliers is the number of positions detected as GPS jumps. They are excluded from the other totals.
+	Outliers int
+}
+
+// Summarize computes the totals of the positions after removing GPS jumps. Steps longer than
+// MaxGap count towards Distance but neither to MovingTime nor StationaryTime, as the state of the
+// machine in between is unknown.
+func Summarize(positions []kis.Position, opts Options) Summary {
+	opts = opts.withDefaults()
+	clean := RemoveOutliers(positions, opts)
+	sum := Summary{Points: len(clean), Outliers: len(positions) - len(clean)}
+	for _, s := range Steps(clean) {
+		sum.Distance += s.Distance
+		switch {
+		case s.Duration > opts.MaxGap:
+		case s.Speed < opts.StationarySpeed:
+			sum.StationaryTime += s.Duration
+		default:
+			sum.MovingTime += s.Duration
+		}
+	}
+	return sum
+}
+
+// Outliers returns the positions that are GPS jumps. The positions of every machine form tracks,
+// each position reachable from the previous one of its track without exceeding MaxSpeed. A position
+// continues the track extended last among those it is reachable from. Otherwise it starts a new
+// track branching off at the latest position it is reachable from, if any. All positions outside
+// the longest track, the earliest one on a tie, are jumps. This covers a single bad fix as well as
+// a run of them, also at the start or end of the positions.
+func Outliers(positions []kis.Position, opts Options) []kis.Position {
+	opts = opts.withDefaults()
+	var jumps []kis.Position
+	for _, ps := range byMachine(positions) {
+		for _, i := range outliers(ps, opts.MaxSpeed) {
+			jumps = append(jumps, ps[i])
+		}
+	}
+	return jumps
+}
+
+// RemoveOutliers returns the positions without GPS jumps, grouped by MachineUUID in order of first
+// occurrence and sorted by Timestamp. The input slice is not modified.
+func RemoveOutliers(positions []kis.Position, opts Options) []kis.Position {
+	opts = opts.withDefaults()
+	var clean []kis.Position
+	for _, ps := range byMachine(positions) {
+		drop := outliers(ps, opts.MaxSpeed)
+		for i, p := range ps {
+			if len(drop) > 0 && drop[0] == i {
+				drop = drop[1:]
+				continue
+			}
+			clean = append(clean, p)
+		}
+	}
+	return clean
+}
+
+// outliers returns the sorted indexes of the GPS jumps of the sorted positions of one machine.
+func outliers(ps []kis.Position, maxSpeed float64) []int {
+	if len(ps) == 0 {
+		return nil
+	}
+	// parent[i] is the previous position on the track of ps[i], or -1; depth[i] is the number of
+	// positions of that track up to ps[i]. ends holds the last position of every track.
+	parent := make([]int, len(ps))
+	depth := make([]int, len(ps))
+	parent[0], depth[0] = -1, 1
+	ends := []int{0}
+	for i := 1; i < len(ps); i++ {
+		e := -1
+		for k, j := range ends {
+			if derivedSpeed(ps[j], ps[i]) <= maxSpeed && (e < 0 || j > ends[e]) {
+				e = k
+			}
+		}
+		if e >= 0 {
+			parent[i] = ends[e]
+			ends[e] = i
+		} else {
+			parent[i] = -1
+			for j := i - 1; j >= 0; j-- {
+				if derivedSpeed(ps[j], ps[i]) <= maxSpeed {
+					parent[i] = j
+					break
+				}
+			}
+			ends = append(ends, i)
+		}
+		depth[i] = 1
+		if parent[i] >= 0 {
+			depth[i] += depth[parent[i]]
+		}
+	}
+	longest := ends[0]
+	for _, j := range ends[1:] {
+		if depth[j] > depth[longest] {
+			longest = j
+		}
+	}
+	keep := make([]bool, len(ps))
+	for j := longest; j >= 0; j = parent[j] {
+		keep[j] = true
+	}
+	var idx []int
+	for i, k := range keep {
+		if !k {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// derivedSpeed returns the speed in km/h needed to get from a to b. Positions at the same time
+// are reachable only if they are at the same place.
+func derivedSpeed(a, b kis.Position) float64 {
+	d := distance(a, b)
+	dt := b.Timestamp.Sub(a.Timestamp.Time).Seconds()
+	if dt <= 0 {
+		if d == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return d / dt * 3.6
+}
+
+// DailyDistance is the distance a machine traveled on a day.
+type DailyDistance struct {
+	MachineUUID string
+	// Date is the start of the day.
+	Date time.Time
+	// Distance is the distance in meters.
+	Distance float64
+}
+
+// DailyOdometer returns the distance traveled per machine and day in loc, or UTC if loc is nil, after
+// removing GPS jumps. A step spanning midnight counts towards the day it ends on. The result is sorted
+// by MachineUUID and Date; days without positions are omitted.
+func DailyOdometer(positions []kis.Position, loc *time.Location, opts Options) []DailyDistance {
+	if loc == nil {
+		loc = time.UTC
+	}
+	type day struct {
+		machineUUID string
+		date        time.Time
+	}
+	clean := RemoveOutliers(positions, opts)
+	totals := make(map[day]float64)
+	for _, p := range clean {
+		totals[day{p.MachineUUID, startOfDay(p.Timestamp.Time, loc)}] = 0
+	}
+	for _, s := range Steps(clean) {
+		totals[day{s.MachineUUID, startOfDay(s.To.Timestamp.Time, loc)}] += s.Distance
+	}
+	days := make([]DailyDistance, 0, len(totals))
+	for d, dist := range totals {
+		days = append(days, DailyDistance{MachineUUID: d.machineUUID, Date: d.date, Distance: dist})
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].MachineUUID != days[j].MachineUUID {
+			return days[i].MachineUUID < days[j].MachineUUID
+		}
+		return days[i].Date.Before(days[j].Date)
+	})
+	return days
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,222 @@
+package track
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+)
+
+var start = time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+
+// at returns a position of machine m1 at 8.0° E, lat° N, minute minutes after start.
+func at(minute float64, lat float64) kis.Position {
+	return kis.Position{
+		MachineUUID: "m1",
+		Latitude:    lat,
+		Longitude:   8,
+		Timestamp:   kis.CustomTime{Time: start.Add(time.Duration(minute * float64(time.Minute)))},
+	}
+}
+
+// withSpeed returns p with the reported speed v in km/h.
+func withSpeed(p kis.Position, v float64) kis.Position {
+	p.Speed = &v
+	return p
+}
+
+// minutes returns the minutes after start of the positions.
+func minutes(ps []kis.Position) []float64 {
+	var m []float64
+	for _, p := range ps {
+		m = append(m, p.Timestamp.Sub(start).Minutes())
+	}
+	return m
+}
+
+func TestOutliers(t *testing.T) {
+	// 0.001° latitude per minute is about 6.7 km/h; 1° is far beyond the default MaxSpeed.
+	tests := []struct {
+		name string
+		lats []float64
+		want []float64
+	}{
+		{"none", []float64{0, 0.001, 0.002, 0.003}, nil},
+		{"single jump", []float64{0, 0.001, 1, 0.003, 0.004}, []float64{2}},
+		{"first point", []float64{1, 0.001, 0.002, 0.003}, []float64{0}},
+		{"first two points", []float64{1, 1.001, 0.002, 0.003, 0.004}, []float64{0, 1}},
+		{"last point", []float64{0, 0.001, 0.002, 1}, []float64{3}},
+		{"several points", []float64{0, 0.001, 1, 1.001, 1.002, 0.005, 0.006, 0.007}, []float64{2, 3, 4}},
+		{"two excursions", []float64{0, 1, 0.002, 0.003, 2, 2.001, 0.006}, []float64{1, 4, 5}},
+		{"two points", []float64{0, 1}, []float64{1}},
+		{"single point", []float64{0}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ps []kis.Position
+			for i, lat := range tt.lats {
+				ps = append(ps, at(float64(i), lat))
+			}
+			if got := minutes(Outliers(ps, Options{})); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Outliers() at minutes %v, want %v", got, tt.want)
+			}
+			clean := RemoveOutliers(ps, Options{})
+			if len(clean)+len(tt.want) != len(ps) {
+				t.Errorf("RemoveOutliers() kept %d of %d positions, want %d", len(clean), len(ps), len(ps)-len(tt.want))
+			}
+			if sum := Summarize(ps, Options{}); sum.Outliers != len(tt.want) || sum.Points != len(clean) {
+				t.Errorf("Summarize() = %+v, want %d outliers and %d points", sum, len(tt.want), len(clean))
+			}
+		})
+	}
+}
+
+func TestOutliersSameTime(t *testing.T) {
+	// positions at the same time but different places cannot both be right
+	ps := []kis.Position{at(0, 0), at(1, 0.001), at(1, 0.002), at(2, 0.002), at(3, 0.003)}
+	if got := minutes(Outliers(ps, Options{})); !reflect.DeepEqual(got, []float64{1}) {
+		t.Errorf("Outliers() at minutes %v, want one of the positions at minute 1", got)
+	}
+	ps = []kis.Position{at(0, 0), at(1, 0.001), at(1, 0.001), at(2, 0.002)}
+	if got := Outliers(ps, Options{}); len(got) != 0 {
+		t.Errorf("Outliers() = %v, want none for a repeated position", got)
+	}
+}
+
+func TestSteps(t *testing.T) {
+	m2 := at(1, 50)
+	m2.MachineUUID = "m2"
+	// unsorted and interleaved
+	ps := []kis.Position{at(2, 0.002), m2, withSpeed(at(1, 0.001), 12), at(0, 0)}
+	steps := Steps(ps)
+	if len(steps) != 2 {
+		t.Fatalf("got %d steps, want 2", len(steps))
+	}
+	first, second := steps[0], steps[1]
+	if !first.From.Timestamp.Equal(start) || first.Duration != time.Minute {
+		t.Errorf("first step from %v lasting %v, want from %v lasting 1m", first.From.Timestamp, first.Duration, start)
+	}
+	if first.Speed != 12 || first.Derived {
+		t.Errorf("first step Speed = %f, Derived = %v, want the reported 12 km/h", first.Speed, first.Derived)
+	}
+	want := distance(at(1, 0.001), at(2, 0.002))
+	if second.Distance != want || second.MachineUUID != "m1" {
+		t.Errorf("second step = %+v, want %f m of m1", second, want)
+	}
+	if !second.Derived || math.Abs(second.Speed-want/60*3.6) > 1e-9 {
+		t.Errorf("second step Speed = %f, Derived = %v, want %f km/h derived", second.Speed, second.Derived, want/60*3.6)
+	}
+	if d := Distance(ps); math.Abs(d-first.Distance-second.Distance) > 1e-9 {
+		t.Errorf("Distance() = %f, want %f", d, first.Distance+second.Distance)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name           string
+		positions      []kis.Position
+		moving         time.Duration
+		stationary     time.Duration
+		distanceMeters float64
+	}{
+		{
+			"reported speeds",
+			[]kis.Position{withSpeed(at(0, 0), 10), withSpeed(at(1, 0.001), 10), withSpeed(at(2, 0.001), 0), withSpeed(at(4, 0.001), 0)},
+			time.Minute, 3 * time.Minute, distance(at(0, 0), at(1, 0.001)),
+		},
+		{
+			"derived speeds",
+			[]kis.Position{at(0, 0), at(1, 0.001), at(2, 0.001), at(4, 0.001)},
+			time.Minute, 3 * time.Minute, distance(at(0, 0), at(1, 0.001)),
+		},
+		{
+			// the gap counts towards the distance only
+			"gap longer than MaxGap",
+			[]kis.Position{at(0, 0), at(1, 0.001), at(31, 0.01), at(32, 0.011)},
+			2 * time.Minute, 0, distance(at(0, 0), at(1, 0.001)) + distance(at(1, 0.001), at(31, 0.01)) + distance(at(31, 0.01), at(32, 0.011)),
+		},
+		{
+			"gap of MaxGap",
+			[]kis.Position{at(0, 0), at(10, 0.001)},
+			0, 10 * time.Minute, distance(at(0, 0), at(10, 0.001)),
+		},
+		{
+			"outlier excluded",
+			[]kis.Position{at(0, 0), at(1, 0.001), at(2, 1), at(3, 0.003)},
+			3 * time.Minute, 0, distance(at(0, 0), at(1, 0.001)) + distance(at(1, 0.001), at(3, 0.003)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum := Summarize(tt.positions, Options{})
+			if sum.MovingTime != tt.moving || sum.StationaryTime != tt.stationary {
+				t.Errorf("MovingTime, StationaryTime = %v, %v, want %v, %v", sum.MovingTime, sum.StationaryTime, tt.moving, tt.stationary)
+			}
+			if math.Abs(sum.Distance-tt.distanceMeters) > 1e-9 {
+				t.Errorf("Distance = %f, want %f", sum.Distance, tt.distanceMeters)
+			}
+		})
+	}
+}
+
+func TestDailyOdometer(t *testing.T) {
+	// 23:50, 23:55, 00:05 and 00:10 UTC, and a jump at 00:07
+	night := start.Add(15*time.Hour + 50*time.Minute).Sub(start).Minutes()
+	ps := []kis.Position{at(night, 0), at(night+5, 0.001), at(night+15, 0.002), at(night+17, 1), at(night+20, 0.003)}
+	before := distance(ps[0], ps[1])
+	across := distance(ps[1], ps[2])
+	after := distance(ps[2], ps[4])
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	plus2 := time.FixedZone("UTC+2", 2*60*60)
+	minus1 := time.FixedZone("UTC-1", -60*60)
+
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want []DailyDistance
+	}{
+		{"UTC", nil, []DailyDistance{
+			{MachineUUID: "m1", Date: day, Distance: before},
+			{MachineUUID: "m1", Date: day.AddDate(0, 0, 1), Distance: across + after},
+		}},
+		// all positions are on 7 May at 01:50 and later
+		{"east of UTC", plus2, []DailyDistance{
+			{MachineUUID: "m1", Date: time.Date(2024, 5, 7, 0, 0, 0, 0, plus2), Distance: before + across + after},
+		}},
+		// all positions are on 6 May at 22:50 and later
+		{"west of UTC", minus1, []DailyDistance{
+			{MachineUUID: "m1", Date: time.Date(2024, 5, 6, 0, 0, 0, 0, minus1), Distance: before + across + after},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DailyOdometer(ps, tt.loc, Options{})
+			if len(got) != len(tt.want) {
+				t.Fatalf("DailyOdometer() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].MachineUUID != tt.want[i].MachineUUID || !got[i].Date.Equal(tt.want[i].Date) || math.Abs(got[i].Distance-tt.want[i].Distance) > 1e-9 {
+					t.Errorf("day %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDailyOdometerGap(t *testing.T) {
+	// the machine was off for two days; the step counts towards the day it ends on, regardless of MaxGap
+	m2 := at(0, 48)
+	m2.MachineUUID = "m2"
+	ps := []kis.Position{at(0, 0), at(3*24*60, 0.1), m2}
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	want := []DailyDistance{
+		{MachineUUID: "m1", Date: day},
+		{MachineUUID: "m1", Date: day.AddDate(0, 0, 3), Distance: distance(ps[0], ps[1])},
+		{MachineUUID: "m2", Date: day},
+	}
+	if got := DailyOdometer(ps, nil, Options{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("DailyOdometer() = %+v, want %+v", got, want)
+	}
+}
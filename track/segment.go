@@ -0,0 +1,234 @@
+// Package track analyses the positions reported by the Kubota API, for example to split them
+// into trips and stops.
+package track
+
+import (
+	"sort"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+	"github.com/maltegrosse/go-kubota-kis-api/internal/geo"
+)
+
+// Default values of the Options fields.
+const (
+	DefaultMaxGap          = 10 * time.Minute
+	DefaultStationarySpeed = 1.0
+	DefaultMinStationary   = 5 * time.Minute
+	DefaultMaxSpeed        = 100.0
+)
+
+// State tells whether a machine was moving or standing still during a segment.
+type State int
+
+const (
+	Moving State = iota
+	Stationary
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	if s == Stationary {
+		return "stationary"
+	}
+	return "moving"
+}
+
+// Options configures the analysis of positions.
+type Options struct {
+	// MaxGap is the longest time between two positions of the same segment, DefaultMaxGap if zero.
+	MaxGap time.Duration
+	// StationarySpeed is the speed in km/h below which a machine stands still, DefaultStationarySpeed if zero.
+	StationarySpeed float64
+	// MinStationary is the shortest stop splitting a trip, DefaultMinStationary if zero. Shorter stops
+	// are part of the surrounding trip.
+	MinStationary time.Duration
+	// IgnoreStatus disables splitting segments on a change of StatusName.
+	IgnoreStatus bool
+	// MaxSpeed is the speed in km/h above which a position is considered a GPS jump, DefaultMaxSpeed if zero.
+	MaxSpeed float64
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxGap <= 0 {
+		o.MaxGap = DefaultMaxGap
+	}
+	if o.StationarySpeed <= 0 {
+		o.StationarySpeed = DefaultStationarySpeed
+	}
+	if o.MinStationary <= 0 {
+		o.MinStationary = DefaultMinStationary
+	}
+	if o.MaxSpeed <= 0 {
+		o.MaxSpeed = DefaultMaxSpeed
+	}
+	return o
+}
+
+// Segment is a period in which a machine was continuously moving or standing still with the same status.
+type Segment struct {
+	MachineUUID    string
+	StatusName     string
+	State          State
+	Start          time.Time
+	End            time.Time
+	StartLatitude  float64
+	StartLongitude float64
+	EndLatitude    float64
+	EndLongitude   float64
+	Duration       time.Duration
+	// Distance is the length of the path through the positions of the segment in meters.
+	Distance float64
+	// MaxSpeed is the highest speed in km/h, reported by the machine or derived from the positions.
+	MaxSpeed float64
+	// AvgSpeed is Distance divided by Duration in km/h.
+	AvgSpeed float64
+	Points   int
+}
+
+// Segments splits the positions into segments. A new segment starts after a gap longer than MaxGap,
+// when the StatusName changes and when the machine starts or stops moving for at least MinStationary.
+// The positions are grouped by MachineUUID, in order of first occurrence, and sorted by Timestamp.
+// The input slice is not modified.
+func Segments(positions []kis.Position, opts Options) []Segment {
+	opts = opts.withDefaults()
+	var segments []Segment
+	for _, ps := range byMachine(positions) {
+		segments = append(segments, segment(ps, opts)...)
+	}
+	return segments
+}
+
+// byMachine groups the positions by MachineUUID and sorts every group by Timestamp.
+func byMachine(positions []kis.Position) [][]kis.Position {
+	index := make(map[string]int)
+	var groups [][]kis.Position
+	for _, p := range positions {
+		i, ok := index[p.MachineUUID]
+		if !ok {
+			i = len(groups)
+			index[p.MachineUUID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], p)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g, func(i, j int) bool { return g[i].Timestamp.Before(g[j].Timestamp.Time) })
+	}
+	return groups
+}
+
+// segment splits the sorted positions of one machine.
+func segment(ps []kis.Position, opts Options) []Segment {
+	if len(ps) == 0 {
+		return nil
+	}
+	// breaks[i] is set if a segment has to start at ps[i] regardless of the state.
+	breaks := make([]bool, len(ps))
+	speeds := make([]float64, len(ps))
+	states := make([]State, len(ps))
+	for i, p := range ps {
+		if i > 0 {
+			prev := ps[i-1]
+			breaks[i] = p.Timestamp.Sub(prev.Timestamp.Time) > opts.MaxGap ||
+				(!opts.IgnoreStatus && p.StatusName != prev.StatusName)
+		}
+		if i == 0 || breaks[i] {
+			speeds[i] = reportedSpeed(p)
+		} else {
+			speeds[i] = speed(ps[i-1], p)
+		}
+		if speeds[i] < opts.StationarySpeed {
+			states[i] = Stationary
+		}
+	}
+	// Stops shorter than MinStationary are treated as moving.
+	for i := 0; i < len(ps); {
+		if states[i] != Stationary {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(ps) && states[j] == Stationary && !breaks[j] {
+			j++
+		}
+		if ps[j-1].Timestamp.Sub(ps[i].Timestamp.Time) < opts.MinStationary && !isolated(breaks, i, j) {
+			for k := i; k < j; k++ {
+				states[k] = Moving
+			}
+		}
+		i = j
+	}
+
+	var segments []Segment
+	start := 0
+	for i := 1; i <= len(ps); i++ {
+		if i < len(ps) && !breaks[i] && states[i] == states[start] {
+			continue
+		}
+		segments = append(segments, newSegment(ps[start:i], speeds[start:i], states[start]))
+		start = i
+	}
+	return segments
+}
+
+// isolated reports whether the stop ps[i:j] is bounded by breaks on both sides. Such a stop is not
+// part of a trip and stays stationary.
+func isolated(breaks []bool, i, j int) bool {
+	return (i == 0 || breaks[i]) && (j == len(breaks) || breaks[j])
+}
+
+func newSegment(ps []kis.Position, speeds []float64, state State) Segment {
+	first, last := ps[0], ps[len(ps)-1]
+	s := Segment{
+		MachineUUID:    first.MachineUUID,
+		StatusName:     first.StatusName,
+		State:          state,
+		Start:          first.Timestamp.Time,
+		End:            last.Timestamp.Time,
+		StartLatitude:  first.Latitude,
+		StartLongitude: first.Longitude,
+		EndLatitude:    last.Latitude,
+		EndLongitude:   last.Longitude,
+		Duration:       last.Timestamp.Sub(first.Timestamp.Time),
+		Points:         len(ps),
+	}
+	for i, p := range ps {
+		if i > 0 {
+			s.Distance += distance(ps[i-1], p)
+		}
+		if speeds[i] > s.MaxSpeed {
+			s.MaxSpeed = speeds[i]
+		}
+	}
+	if s.Duration > 0 {
+		s.AvgSpeed = s.Distance / s.Duration.Seconds() * 3.6
+	}
+	return s
+}
+
+// distance returns the distance between two positions in meters.
+func distance(a, b kis.Position) float64 {
+	return geo.Distance(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+}
+
+// speed returns the speed of b in km/h. If b has no reported speed, it is derived from the
+// distance to the previous position a.
+func speed(a, b kis.Position) float64 {
+	if b.Speed != nil {
+		return *b.Speed
+	}
+	dt := b.Timestamp.Sub(a.Timestamp.Time).Seconds()
+	if dt <= 0 {
+		return 0
+	}
+	return distance(a, b) / dt * 3.6
+}
+
+// reportedSpeed returns the speed of p in km/h, or 0 if the machine did not report one.
+func reportedSpeed(p kis.Position) float64 {
+	if p.Speed != nil {
+		return *p.Speed
+	}
+	return 0
+}
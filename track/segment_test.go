@@ -0,0 +1,174 @@
+package track
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	kis "github.com/maltegrosse/go-kubota-kis-api"
+)
+
+// drive returns positions from minute from to minute to, one per minute, moving north by 0.001°
+// (about 6.7 km/h) per minute with the reported speed v, starting at lat.
+func drive(from, to int, lat, v float64) []kis.Position {
+	var ps []kis.Position
+	for m := from; m <= to; m++ {
+		ps = append(ps, withSpeed(at(float64(m), lat+float64(m-from)*0.001), v))
+	}
+	return ps
+}
+
+// stand returns positions from minute from to minute to, one per minute, at lat with speed 0.
+func stand(from, to int, lat float64) []kis.Position {
+	var ps []kis.Position
+	for m := from; m <= to; m++ {
+		ps = append(ps, withSpeed(at(float64(m), lat), 0))
+	}
+	return ps
+}
+
+func withStatus(ps []kis.Position, status string) []kis.Position {
+	for i := range ps {
+		ps[i].StatusName = status
+	}
+	return ps
+}
+
+func concat(parts ...[]kis.Position) []kis.Position {
+	var ps []kis.Position
+	for _, p := range parts {
+		ps = append(ps, p...)
+	}
+	return ps
+}
+
+type wantSegment struct {
+	state      State
+	start, end float64
+	points     int
+}
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions []kis.Position
+		opts      Options
+		want      []wantSegment
+	}{
+		{
+			"single trip",
+			drive(0, 5, 0, 10),
+			Options{},
+			[]wantSegment{{Moving, 0, 5, 6}},
+		},
+		{
+			"gap",
+			concat(drive(0, 2, 0, 10), drive(20, 22, 0.003, 10)),
+			Options{},
+			[]wantSegment{{Moving, 0, 2, 3}, {Moving, 20, 22, 3}},
+		},
+		{
+			"gap within MaxGap",
+			concat(drive(0, 2, 0, 10), drive(20, 22, 0.003, 10)),
+			Options{MaxGap: 20 * time.Minute},
+			[]wantSegment{{Moving, 0, 22, 6}},
+		},
+		{
+			"status change",
+			concat(withStatus(drive(0, 2, 0, 10), "working"), withStatus(drive(3, 5, 0.003, 10), "idle")),
+			Options{},
+			[]wantSegment{{Moving, 0, 2, 3}, {Moving, 3, 5, 3}},
+		},
+		{
+			"status change ignored",
+			concat(withStatus(drive(0, 2, 0, 10), "working"), withStatus(drive(3, 5, 0.003, 10), "idle")),
+			Options{IgnoreStatus: true},
+			[]wantSegment{{Moving, 0, 5, 6}},
+		},
+		{
+			"short stop folded into the trip",
+			concat(drive(0, 3, 0, 10), stand(4, 6, 0.003), drive(7, 9, 0.003, 10)),
+			Options{},
+			[]wantSegment{{Moving, 0, 9, 10}},
+		},
+		{
+			"stop of MinStationary",
+			concat(drive(0, 3, 0, 10), stand(4, 9, 0.003), drive(10, 12, 0.003, 10)),
+			Options{},
+			[]wantSegment{{Moving, 0, 3, 4}, {Stationary, 4, 9, 6}, {Moving, 10, 12, 3}},
+		},
+		{
+			"short stop at the end",
+			concat(drive(0, 3, 0, 10), stand(4, 5, 0.003)),
+			Options{},
+			[]wantSegment{{Moving, 0, 5, 6}},
+		},
+		{
+			"isolated short stop",
+			concat(drive(0, 2, 0, 10), stand(20, 21, 0.002), drive(40, 42, 0.002, 10)),
+			Options{},
+			[]wantSegment{{Moving, 0, 2, 3}, {Stationary, 20, 21, 2}, {Moving, 40, 42, 3}},
+		},
+		{
+			"isolated single position",
+			stand(0, 0, 0),
+			Options{},
+			[]wantSegment{{Stationary, 0, 0, 1}},
+		},
+		{
+			"unsorted",
+			func() []kis.Position {
+				ps := drive(0, 3, 0, 10)
+				ps[0], ps[3] = ps[3], ps[0]
+				return ps
+			}(),
+			Options{},
+			[]wantSegment{{Moving, 0, 3, 4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Segments(tt.positions, tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d segments %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i, s := range got {
+				w := tt.want[i]
+				if s.State != w.state || !s.Start.Equal(at(w.start, 0).Timestamp.Time) || !s.End.Equal(at(w.end, 0).Timestamp.Time) || s.Points != w.points {
+					t.Errorf("segment %d = %s %v to %v with %d points, want %s minute %v to %v with %d points",
+						i, s.State, s.Start, s.End, s.Points, w.state, w.start, w.end, w.points)
+				}
+			}
+		})
+	}
+}
+
+func TestSegmentsDerivedSpeed(t *testing.T) {
+	// no reported speeds: 0.001° per minute is about 6.7 km/h, followed by a stop of 10 minutes
+	var ps []kis.Position
+	for m := 0; m <= 5; m++ {
+		ps = append(ps, at(float64(m), float64(m)*0.001))
+	}
+	for m := 6; m <= 15; m++ {
+		ps = append(ps, at(float64(m), 0.005))
+	}
+	got := Segments(ps, Options{})
+	if len(got) != 2 || got[0].State != Moving || got[1].State != Stationary {
+		t.Fatalf("segments = %+v, want a moving and a stationary one", got)
+	}
+	trip := got[0]
+	// the first position has no previous one to derive its speed from and belongs to the trip
+	if trip.Points != 6 || !trip.Start.Equal(start) {
+		t.Errorf("trip starts at %v with %d points, want %v with 6", trip.Start, trip.Points, start)
+	}
+	want := distance(at(0, 0), at(1, 0.001)) / 60 * 3.6
+	if math.Abs(trip.MaxSpeed-want) > 0.01 {
+		t.Errorf("MaxSpeed = %f, want %f", trip.MaxSpeed, want)
+	}
+	if math.Abs(trip.AvgSpeed-want) > 0.01 {
+		t.Errorf("AvgSpeed = %f, want %f", trip.AvgSpeed, want)
+	}
+	if stop := got[1]; stop.Distance != 0 || stop.MaxSpeed != 0 || stop.Points != 10 {
+		t.Errorf("stop = %+v, want 10 points without distance and speed", stop)
+	}
+}
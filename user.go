@@ -1,11 +1,6 @@
 package kis
 
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
-)
+import "context"
 
 // User represents the User information returned by the Kubota API.
 type User struct {
@@ -24,60 +19,36 @@ type User struct {
 
 // GetUserByMobilePhone retrieves user information by mobile phone number.
 func (k *Kubota) GetUserByMobilePhone(mobilePhone string) (*User, error) {
-	return k.getUser("mobilePhone", mobilePhone)
+	return payload(k.getUser(context.Background(), "mobilePhone", mobilePhone))
 }
 
-// GetUserByUserName retrieves user information by username.
-func (k *Kubota) GetUserByUserName(userName string) (*User, error) {
-	return k.getUser("userName", userName)
+// GetUserByMobilePhoneContext is like GetUserByMobilePhone but uses ctx to bound the request.
+func (k *Kubota) GetUserByMobilePhoneContext(ctx context.Context, mobilePhone string) (*User, error) {
+	return payload(k.getUser(ctx, "mobilePhone", mobilePhone))
 }
 
-// getUser is a helper function to retrieve user information based on a given field.
-func (k *Kubota) getUser(field, value string) (*User, error) {
-	// Construct the request URL
-	apiURL := fmt.Sprintf("%s/api/v1/user?%s=%s", k.authentication.Endpoint, field, value)
-
-	// Make the request
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating user request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+k.authentication.getToken())
+// GetUserByMobilePhoneWithMeta is like GetUserByMobilePhoneContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetUserByMobilePhoneWithMeta(ctx context.Context, mobilePhone string) (*User, ResponseMeta, error) {
+	return k.getUser(ctx, "mobilePhone", mobilePhone)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making user request: %w", err)
-	}
-	defer resp.Body.Close()
+// GetUserByUserName retrieves user information by username.
+func (k *Kubota) GetUserByUserName(userName string) (*User, error) {
+	return payload(k.getUser(context.Background(), "userName", userName))
+}
 
-	// Handle the response
-	if resp.StatusCode != http.StatusOK {
-		var errResponse = errorResponse{}
-		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-			return nil, fmt.Errorf("error decoding error response: %w", err)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// get the header and retry after the specified time
-			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				errResponse.Details = append(errResponse.Details, fmt.Sprintf("Retry-After: %s", retryAfter))
-			}
-		}
-		return nil, fmt.Errorf("error: %s with statuscode: %d, type %s, details: %s", errResponse.Title, errResponse.Status, errResponse.Type, strings.Join(errResponse.Details, ", "))
-	}
+// GetUserByUserNameContext is like GetUserByUserName but uses ctx to bound the request.
+func (k *Kubota) GetUserByUserNameContext(ctx context.Context, userName string) (*User, error) {
+	return payload(k.getUser(ctx, "userName", userName))
+}
 
-	// Unmarshal the response
-	var userResponse struct {
-		Status   int    `json:"Status"`
-		Resource string `json:"Resource"`
-		Payload  User   `json:"Payload"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&userResponse); err != nil {
-		return nil, fmt.Errorf("error decoding user response: %w", err)
-	}
+// GetUserByUserNameWithMeta is like GetUserByUserNameContext but also returns the envelope metadata of the response.
+func (k *Kubota) GetUserByUserNameWithMeta(ctx context.Context, userName string) (*User, ResponseMeta, error) {
+	return k.getUser(ctx, "userName", userName)
+}
 
-	// Return the user information
-	return &userResponse.Payload, nil
+// getUser is a helper function to retrieve user information based on a given field.
+func (k *Kubota) getUser(ctx context.Context, field, value string) (*User, ResponseMeta, error) {
+	call := CallInfo{Operation: "GetUser", Endpoint: "user", Field: field}
+	return do[*User](ctx, k, call, value)
 }